@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/MhemedAbderrahmen/ut/config"
+	"github.com/MhemedAbderrahmen/ut/uploadthing"
+
+	"github.com/spf13/cobra"
+)
+
+var aclByCustomID bool
+
+var aclCmd = &cobra.Command{
+	Use:   "acl",
+	Short: "Manage who can access your files",
+	Long: `Manage the access control list (ACL) of files on UploadThing.
+
+Public files (public-read) can be downloaded by anyone with their URL. Private
+files need a signed URL, which 'ut fetch --private' requests with your secret
+key. New files are public unless pushed with 'ut push --acl private'.`,
+}
+
+var aclSetCmd = &cobra.Command{
+	Use:   "set <fileKey> [fileKey2]... <acl>",
+	Short: "Make files public or private",
+	Long: `Set the ACL of one or more files to public-read or private.
+
+Your app must allow ACL overrides in the UploadThing dashboard.
+
+Examples:
+  ut acl set abc123-invoice.pdf private              # Lock down a file
+  ut acl set abc123-a.jpg abc123-b.jpg public-read   # Publish several files
+  ut acl set --custom-id avatar-42 private           # Identify by custom ID`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		keys, acl := args[:len(args)-1], args[len(args)-1]
+		if err := setACL(cmd.Context(), keys, acl); err != nil {
+			fmt.Fprintf(os.Stderr, "Error setting ACL: %v\n", err)
+			exit(cmd.Context(), 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(aclCmd)
+	aclCmd.AddCommand(aclSetCmd)
+
+	aclSetCmd.Flags().BoolVar(&aclByCustomID, "custom-id", false, "Treat arguments as custom IDs instead of file keys")
+}
+
+type ACLResult struct {
+	Key     string `json:"key" yaml:"key"`
+	ACL     string `json:"acl" yaml:"acl"`
+	Updated bool   `json:"updated" yaml:"updated"`
+}
+
+func (r ACLResult) csvHeader() []string {
+	return []string{"key", "acl", "updated"}
+}
+
+func (r ACLResult) csvRow() []string {
+	return []string{r.Key, r.ACL, strconv.FormatBool(r.Updated)}
+}
+
+// validateACL checks that acl is one UploadThing accepts.
+func validateACL(acl string) error {
+	switch acl {
+	case uploadthing.ACLPublicRead, uploadthing.ACLPrivate:
+		return nil
+	}
+	return fmt.Errorf("invalid ACL %q (use %s or %s)", acl, uploadthing.ACLPublicRead, uploadthing.ACLPrivate)
+}
+
+// setACL updates every key in one updateACL call, so either all files change
+// or none do.
+func setACL(ctx context.Context, keys []string, acl string) error {
+	if err := validateACL(acl); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	client := newClient(cfg)
+
+	req := uploadthing.UpdateACLRequest{}
+	for _, key := range keys {
+		if aclByCustomID {
+			req.Updates = append(req.Updates, uploadthing.ACLUpdate{CustomID: key, ACL: acl})
+		} else {
+			req.Updates = append(req.Updates, uploadthing.ACLUpdate{FileKey: key, ACL: acl})
+		}
+	}
+
+	resp, err := client.UpdateACL(ctx, req)
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return errors.New("UploadThing did not accept the ACL change")
+	}
+
+	if !isTableOutput() {
+		records := make([]record, 0, len(keys))
+		for _, key := range keys {
+			records = append(records, ACLResult{Key: key, ACL: acl, Updated: true})
+		}
+		return writeRecords(records...)
+	}
+	for _, key := range keys {
+		fmt.Printf("✓ %s is now %s\n", key, acl)
+	}
+	return nil
+}
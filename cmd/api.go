@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/MhemedAbderrahmen/ut/config"
+	"github.com/MhemedAbderrahmen/ut/uploadthing"
+)
+
+// newClient returns an API client for cfg that reports progress through
+// infof and uses the retry settings of the global flags and the part
+// settings of the push flags.
+func newClient(cfg *config.Config) *uploadthing.Client {
+	client := uploadthing.NewClient(cfg.SecretKey)
+	client.AppID = cfg.AppName
+	if cfg.APIURL != "" {
+		client.BaseURL = strings.TrimSuffix(cfg.APIURL, "/")
+	}
+	if cfg.FileHost != "" {
+		client.FileHost = cfg.FileHost
+	}
+	client.PartConcurrency = partConcurrency
+	client.PartRetries = partRetries
+	client.Retries = retries
+	client.RetryMaxWait = retryMaxWait
+	client.Logf = infof
+	return client
+}
@@ -0,0 +1,363 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MhemedAbderrahmen/ut/config"
+	"github.com/MhemedAbderrahmen/ut/uploadthing"
+)
+
+var (
+	keysFile            string
+	nameGlob            string
+	sinceFilter         string
+	downloadConcurrency int
+)
+
+// downloadTarget is a remote file to download. Name is the local file name;
+// when empty it is derived from the key.
+type downloadTarget struct {
+	Key  string
+	Name string
+}
+
+func isBulkDownload(args []string) bool {
+	return len(args) != 1 || args[0] == "-" || keysFile != "" || nameGlob != "" || sinceFilter != ""
+}
+
+func runBulkDownload(ctx context.Context, args []string) (int, error) {
+	targets, err := collectDownloadTargets(ctx, args)
+	if err != nil {
+		return 0, err
+	}
+	if len(targets) == 0 {
+		infof("No files to download.\n")
+		return 0, nil
+	}
+	if err := assignDownloadNames(targets); err != nil {
+		return 0, err
+	}
+	if isPrivate {
+		// Load the secret key before starting the workers so that a secret
+		// store prompts once instead of in every worker.
+		if _, err := config.LoadConfig(); err != nil {
+			return 0, fmt.Errorf("failed to load config (API key required for private files): %w", err)
+		}
+	}
+
+	workers := downloadConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	infof("Downloading %d files (%d at a time)...\n", len(targets), workers)
+
+	rw := newRecordWriter(os.Stdout, outputFormat)
+	defer rw.Close()
+
+	var (
+		mu         sync.Mutex
+		wg         sync.WaitGroup
+		done       int
+		downloaded int
+		failed     int
+	)
+
+	jobs := make(chan downloadTarget)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				result, err := runDownload(ctx, target, true)
+				if err != nil {
+					result = &DownloadResult{Key: target.Key, Error: err.Error()}
+				}
+
+				mu.Lock()
+				done++
+				if err != nil {
+					failed++
+					fmt.Fprintf(os.Stderr, "[%d/%d] ✗ %s: %v\n", done, len(targets), target.Key, err)
+				} else {
+					downloaded++
+					infof("[%d/%d] ✓ %s\n", done, len(targets), result.Path)
+				}
+				if isTableOutput() {
+					if err == nil {
+						fmt.Printf("%s (%s)\n", result.Path, formatFileSize(result.Size))
+					}
+				} else if err := rw.Write(result); err != nil {
+					fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	started := 0
+feed:
+	for _, target := range targets {
+		select {
+		case jobs <- target:
+			started++
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		infof("\n%d downloaded, %d failed, %d not started.\n", downloaded, failed, len(targets)-started)
+		return failed, context.Cause(ctx)
+	}
+	infof("\n%d downloaded, %d failed.\n", downloaded, failed)
+	return failed, nil
+}
+
+// collectDownloadTargets gathers keys from the arguments, stdin, --keys-file
+// and, when --name-glob or --since is given, from the list API. With
+// --custom-id, the keys given by the user are custom IDs and are resolved
+// through the list API.
+func collectDownloadTargets(ctx context.Context, args []string) ([]downloadTarget, error) {
+	var targets []downloadTarget
+	seen := map[string]bool{}
+	add := func(t downloadTarget) {
+		if !seen[t.Key] {
+			seen[t.Key] = true
+			targets = append(targets, t)
+		}
+	}
+
+	var given []string
+	for _, arg := range args {
+		if arg != "-" {
+			given = append(given, arg)
+			continue
+		}
+		keys, err := readKeys(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keys from stdin: %w", err)
+		}
+		given = append(given, keys...)
+	}
+
+	if keysFile != "" {
+		file, err := os.Open(keysFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open keys file: %w", err)
+		}
+		keys, err := readKeys(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keys file: %w", err)
+		}
+		given = append(given, keys...)
+	}
+
+	if fetchByCustomID && len(given) > 0 {
+		resolved, err := resolveCustomIDs(ctx, given)
+		if err != nil {
+			return nil, err
+		}
+		for _, target := range resolved {
+			add(target)
+		}
+	} else {
+		for _, key := range given {
+			add(downloadTarget{Key: key})
+		}
+	}
+
+	if nameGlob != "" || sinceFilter != "" {
+		files, err := listMatchingFiles(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			add(downloadTarget{Key: file.FileKey, Name: file.Name})
+		}
+	}
+
+	return targets, nil
+}
+
+// assignDownloadNames gives every target the local name it is saved under
+// before any download starts. Files that would land on the same path, such as
+// two uploads both named photo.jpg, are saved with a part of their key
+// appended to the name instead, so that concurrent downloads never share an
+// output or .part file. Names are compared case-insensitively for case-insensitive file
+// systems.
+func assignDownloadNames(targets []downloadTarget) error {
+	pathKey := func(name string) string {
+		return strings.ToLower(path.Clean(filepath.ToSlash(name)))
+	}
+
+	count := map[string]int{}
+	for i := range targets {
+		if targets[i].Name == "" {
+			targets[i].Name = extractFilenameFromKey(targets[i].Key)
+		}
+		count[pathKey(targets[i].Name)]++
+	}
+
+	renamed := 0
+	for i, target := range targets {
+		if count[pathKey(target.Name)] < 2 {
+			continue
+		}
+		ext := path.Ext(target.Name)
+		targets[i].Name = strings.TrimSuffix(target.Name, ext) + "-" + keySuffix(target.Key, target.Name) + ext
+		renamed++
+	}
+	if renamed == 0 {
+		return nil
+	}
+
+	taken := map[string]string{}
+	for _, target := range targets {
+		p := pathKey(target.Name)
+		if other, dup := taken[p]; dup {
+			return fmt.Errorf("files %s and %s would both be saved as %s", other, target.Key, target.Name)
+		}
+		taken[p] = target.Key
+	}
+	infof("%d files share a name with another file and are saved with part of their key appended.\n", renamed)
+	return nil
+}
+
+// keySuffix returns the part of key that tells apart files named name: the
+// prefix in front of the name for keys like "abc123-photo.jpg", otherwise a
+// short hash of the key.
+func keySuffix(key, name string) string {
+	if prefix, found := strings.CutSuffix(key, "-"+path.Base(name)); found && prefix != "" {
+		return prefix
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:4])
+}
+
+// resolveCustomIDs looks up the files with the given custom IDs, in the same
+// order, so that they can be downloaded by key under their own names.
+func resolveCustomIDs(ctx context.Context, ids []string) ([]downloadTarget, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration (API key required to look up custom IDs): %w", err)
+	}
+
+	infof("Looking up custom IDs...\n")
+
+	files, err := findFiles(ctx, newClient(cfg), nil, ids)
+	if err != nil {
+		return nil, err
+	}
+	if missing := missingFiles(files, ids, true); len(missing) > 0 {
+		return nil, fmt.Errorf("no file with custom ID %s", strings.Join(missing, ", "))
+	}
+
+	byID := make(map[string]uploadthing.FileInfo, len(files))
+	for _, file := range files {
+		byID[file.CustomID] = file
+	}
+	targets := make([]downloadTarget, 0, len(ids))
+	for _, id := range ids {
+		targets = append(targets, downloadTarget{Key: byID[id].FileKey, Name: byID[id].Name})
+	}
+	return targets, nil
+}
+
+func listMatchingFiles(ctx context.Context) ([]uploadthing.FileInfo, error) {
+	var glob *globPattern
+	if nameGlob != "" {
+		g, err := compileGlob(nameGlob)
+		if err != nil {
+			return nil, err
+		}
+		glob = &g
+	}
+
+	var since time.Time
+	if sinceFilter != "" {
+		t, err := parseSince(sinceFilter, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		since = t
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	infof("Fetching remote file list...\n")
+
+	var matched []uploadthing.FileInfo
+	it := newClient(cfg).Files(defaultPageSize, 0)
+	for it.HasNext() {
+		files, err := it.Next(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files: %w", err)
+		}
+		for _, file := range files {
+			if glob != nil && !glob.match(file.Name, false) {
+				continue
+			}
+			if !since.IsZero() && file.UploadedTime().Before(since) {
+				continue
+			}
+			matched = append(matched, file)
+		}
+	}
+	return matched, nil
+}
+
+// parseSince accepts a date, an RFC 3339 timestamp or a duration such as
+// "36h" or "7d" measured back from now.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if days, found := strings.CutSuffix(value, "d"); found {
+		if n, err := strconv.Atoi(days); err == nil {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --since value %q (use a date like 2006-01-02 or a duration like 24h or 7d)", value)
+}
+
+// bulkOutputPath places filename inside the --output directory, keeping any
+// subdirectories in the name but refusing paths that escape the directory.
+func bulkOutputPath(filename string) (string, error) {
+	rel := filepath.FromSlash(filename)
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("refusing to write outside the output directory: %q", filename)
+	}
+
+	dir := outputPath
+	if dir == "" {
+		dir = "."
+	}
+
+	fullPath := filepath.Join(dir, rel)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	return fullPath, nil
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAssignDownloadNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []downloadTarget
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "unique names",
+			targets: []downloadTarget{{Key: "k1", Name: "a.jpg"}, {Key: "k2", Name: "b.jpg"}},
+			want:    []string{"a.jpg", "b.jpg"},
+		},
+		{
+			name:    "names derived from keys",
+			targets: []downloadTarget{{Key: "abc-photo.jpg"}, {Key: "def-photo.jpg"}},
+			want:    []string{"photo-abc.jpg", "photo-def.jpg"},
+		},
+		{
+			name:    "same name",
+			targets: []downloadTarget{{Key: "k1", Name: "photo.jpg"}, {Key: "k2", Name: "photo.jpg"}, {Key: "k3", Name: "other.jpg"}},
+			want:    []string{"photo-" + keySuffix("k1", "photo.jpg") + ".jpg", "photo-" + keySuffix("k2", "photo.jpg") + ".jpg", "other.jpg"},
+		},
+		{
+			name:    "key ends with the name",
+			targets: []downloadTarget{{Key: "abc-photo.jpg", Name: "photo.jpg"}, {Key: "xyz", Name: "photo.jpg"}},
+			want:    []string{"photo-abc.jpg", "photo-" + keySuffix("xyz", "photo.jpg") + ".jpg"},
+		},
+		{
+			name:    "differs only in case",
+			targets: []downloadTarget{{Key: "k1", Name: "Photo.JPG"}, {Key: "k2", Name: "photo.jpg"}},
+			want:    []string{"Photo-" + keySuffix("k1", "Photo.JPG") + ".JPG", "photo-" + keySuffix("k2", "photo.jpg") + ".jpg"},
+		},
+		{
+			name:    "same path spelled differently",
+			targets: []downloadTarget{{Key: "x-a", Name: "dir/a"}, {Key: "y-a", Name: "dir//a"}},
+			want:    []string{"dir/a-x", "dir//a-y"},
+		},
+		{
+			name:    "renamed file still collides",
+			targets: []downloadTarget{{Key: "k1-a.txt", Name: "a.txt"}, {Key: "k2-a.txt", Name: "a.txt"}, {Key: "k3", Name: "a-k1.txt"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assignDownloadNames(tt.targets)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("assignDownloadNames: %v", err)
+			}
+			var got []string
+			for _, target := range tt.targets {
+				got = append(got, target.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("names = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeySuffix(t *testing.T) {
+	tests := []struct {
+		key, name, want string
+	}{
+		{"abc123-photo.jpg", "photo.jpg", "abc123"},
+		{"a-b-photo.jpg", "photo.jpg", "a-b"},
+		{"abc123-photo.jpg", "dir/photo.jpg", "abc123"},
+	}
+	for _, tt := range tests {
+		if got := keySuffix(tt.key, tt.name); got != tt.want {
+			t.Errorf("keySuffix(%q, %q) = %q, want %q", tt.key, tt.name, got, tt.want)
+		}
+	}
+
+	hashed := keySuffix("3Xk9zQ7pL2mN", "photo.jpg")
+	if len(hashed) != 8 || hashed == keySuffix("3Xk9zQ7pL2mO", "photo.jpg") {
+		t.Errorf("keySuffix without a name in the key = %q, want a short hash that differs per key", hashed)
+	}
+}
@@ -2,18 +2,34 @@ package cmd
 
 import (
 	"fmt"
+	"mime"
 	"os"
-	"path/filepath"
+	"strings"
+
+	"github.com/MhemedAbderrahmen/ut/config"
 
-	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
-	"gopkg.in/yaml.v2"
 )
 
-type ConfigFile struct {
-	AppName   string `yaml:"appname"`
-	SecretKey string `yaml:"secretkey"`
+type ConfigView struct {
+	ConfigFile string `json:"configFile" yaml:"configFile"`
+	Profile    string `json:"profile" yaml:"profile"`
+	AppName    string `json:"appName" yaml:"appName"`
+	Regions    string `json:"regions,omitempty" yaml:"regions,omitempty"`
+	SecretKey  string `json:"secretKey" yaml:"secretKey"`
+	Source     string `json:"source" yaml:"source"`
+	Store      string `json:"store,omitempty" yaml:"store,omitempty"`
+	APIURL     string `json:"apiUrl,omitempty" yaml:"apiUrl,omitempty"`
+	FileHost   string `json:"fileHost,omitempty" yaml:"fileHost,omitempty"`
+}
+
+func (v ConfigView) csvHeader() []string {
+	return []string{"configFile", "profile", "appName", "regions", "secretKey", "source", "store", "apiUrl", "fileHost"}
+}
+
+func (v ConfigView) csvRow() []string {
+	return []string{v.ConfigFile, v.Profile, v.AppName, v.Regions, v.SecretKey, v.Source, v.Store, v.APIURL, v.FileHost}
 }
 
 var configCmd = &cobra.Command{
@@ -22,19 +38,103 @@ var configCmd = &cobra.Command{
 	Long:  `Configure your UploadThing credentials and settings.`,
 }
 
+var (
+	secretStore string
+	migrateAll  bool
+)
+
 var setSecretCmd = &cobra.Command{
-	Use:   "set-secret <secret-key>",
+	Use:   "set-secret [secret-key | -]",
 	Short: "Set your UploadThing secret key",
-	Long:  `Set your UploadThing secret API key for authentication.`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Set your UploadThing secret API key for authentication.
+
+By default the key is kept in the profile's current secret store, which is
+plain text in config.yml unless another store was chosen. Use --store to keep
+it elsewhere:
+
+  keyring  the OS keyring (Keychain, Credential Manager or Secret Service)
+  file     a file encrypted with a passphrase, for machines without a keyring;
+           set UT_SECRET_PASSPHRASE to avoid the prompt
+  plain    plain text in config.yml
+
+When no key or "-" is given, the key is read from stdin (with a hidden prompt
+on a terminal) so it does not end up in your shell history.
+
+Examples:
+  ut config set-secret sk_live_xxx --store keyring
+  ut config set-secret                            # Prompt for the key
+  pass show uploadthing | ut config set-secret -  # Read the key from stdin`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		secretKey := args[0]
-		err := setSecretKey(secretKey)
+		secretKey, err := readSecretArg(args)
+		if err == nil {
+			err = setSecretKey(secretKey, secretStore)
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error setting secret key: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Println("Secret key updated successfully!")
+		infof("Secret key updated successfully!\n")
+	},
+}
+
+var unsetContentType bool
+
+var setContentTypeCmd = &cobra.Command{
+	Use:   "set-content-type <extension> [mime-type]",
+	Short: "Map a file extension to the Content-Type used for uploads",
+	Long: `Map a file extension to the Content-Type sent when uploading files with it,
+overriding detection. The mapping applies to every profile.
+
+Examples:
+  ut config set-content-type .webmanifest application/manifest+json
+  ut config set-content-type .glb model/gltf-binary
+  ut config set-content-type .glb --unset      # Go back to detection`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if unsetContentType {
+			return cobra.ExactArgs(1)(cmd, args)
+		}
+		return cobra.ExactArgs(2)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		contentType := ""
+		if !unsetContentType {
+			contentType = args[1]
+		}
+		ext, err := setContentType(args[0], contentType)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error setting content type: %v\n", err)
+			os.Exit(1)
+		}
+		if contentType == "" {
+			infof("Removed the content type mapping for %s.\n", ext)
+			return
+		}
+		infof("Files ending in %s will be uploaded as %s.\n", ext, contentType)
+	},
+}
+
+var migrateSecretCmd = &cobra.Command{
+	Use:   "migrate-secret",
+	Short: "Move secret keys to another secret store",
+	Long: `Move the secret key of the active profile, or of every profile with --all,
+into the given secret store and remove it from the old one.
+
+Examples:
+  ut config migrate-secret --store keyring         # Move the active profile's key
+  ut config migrate-secret --store file --all      # Encrypt every profile's key`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		migrated, err := migrateSecrets(secretStore, migrateAll)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error migrating secret keys: %v\n", err)
+			os.Exit(1)
+		}
+		if migrated == 0 {
+			infof("No secret keys to migrate.\n")
+			return
+		}
+		infof("Moved %d secret key(s) to the %s store.\n", migrated, secretStore)
 	},
 }
 
@@ -51,15 +151,46 @@ var showConfigCmd = &cobra.Command{
 	},
 }
 
+var unsetConfigPath bool
+
 var setConfigPathCmd = &cobra.Command{
 	Use:   "set-config-path <path>",
 	Short: "Set configuration file path",
-	Long:  `Set a custom path for the configuration file.`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Set a custom path for the configuration file.
+
+The path is remembered for future runs. The config file location is resolved
+from the global --config flag, then the UT_CONFIG environment variable, then
+the path saved by this command, and finally the default location
+(~/.ut-cli/config.yml, or $XDG_CONFIG_HOME/ut/config.yml when ~/.ut-cli does
+not exist).
+
+Examples:
+  ut config set-config-path ~/dotfiles/ut.yml   # Use a custom config file
+  ut config set-config-path --unset             # Go back to the default location`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if unsetConfigPath {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
-		configPath := args[0]
-		fmt.Printf("Custom config path set to: %s\n", configPath)
-		fmt.Println("Note: This feature is not yet implemented.")
+		configPath := ""
+		if !unsetConfigPath {
+			configPath = args[0]
+		}
+		savedPath, err := config.SetPersistedPath(configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error setting config path: %v\n", err)
+			os.Exit(1)
+		}
+		if savedPath == "" {
+			infof("Custom config path removed; using the default location.\n")
+			return
+		}
+		infof("Custom config path set to: %s\n", savedPath)
+		if _, err := os.Stat(savedPath); os.IsNotExist(err) {
+			infof("Note: %s does not exist yet. Use 'ut config set-secret <key>' to create it.\n", savedPath)
+		}
 	},
 }
 
@@ -68,68 +199,183 @@ func init() {
 	configCmd.AddCommand(setSecretCmd)
 	configCmd.AddCommand(showConfigCmd)
 	configCmd.AddCommand(setConfigPathCmd)
+	configCmd.AddCommand(migrateSecretCmd)
+	configCmd.AddCommand(setContentTypeCmd)
+
+	config.PassphraseFunc = readPassphrase
+
+	setSecretCmd.Flags().StringVar(&secretStore, "store", "", "Where to keep the secret key (keyring|file|plain)")
+	migrateSecretCmd.Flags().StringVar(&secretStore, "store", "", "Secret store to move keys to (keyring|file|plain)")
+	migrateSecretCmd.Flags().BoolVar(&migrateAll, "all", false, "Migrate every profile instead of only the active one")
+	migrateSecretCmd.MarkFlagRequired("store")
+
+	setContentTypeCmd.Flags().BoolVar(&unsetContentType, "unset", false, "Remove the mapping and detect the type again")
+	setConfigPathCmd.Flags().BoolVar(&unsetConfigPath, "unset", false, "Remove the saved path and use the default location")
 }
 
-func setSecretKey(secretKey string) error {
-	configDir, configFile, err := getConfigPaths()
+func setSecretKey(secretKey, store string) error {
+	if secretKey == "" {
+		return fmt.Errorf("secret key cannot be empty")
+	}
+	if store != "" && !config.ValidStore(store) {
+		return fmt.Errorf("invalid --store %q (use keyring, file or plain)", store)
+	}
+
+	f, err := config.ReadOrEmpty()
 	if err != nil {
 		return err
 	}
 
-	if err := ensureConfigDir(configDir); err != nil {
+	if err := config.StoreSecret(f, config.ActiveProfile(f), store, secretKey); err != nil {
 		return err
 	}
 
-	var cfg ConfigFile
-	if data, err := os.ReadFile(configFile); err == nil {
-		yaml.Unmarshal(data, &cfg)
-	}
+	return config.Write(f)
+}
 
-	cfg.SecretKey = secretKey
+// setContentType maps ext to contentType in the config file, or removes the
+// mapping when contentType is empty. It returns the normalized extension.
+func setContentType(ext, contentType string) (string, error) {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	if len(ext) < 2 || strings.ContainsAny(ext[1:], "./\\") {
+		return "", fmt.Errorf("invalid extension %q", ext)
+	}
+	if contentType != "" {
+		if _, _, err := mime.ParseMediaType(contentType); err != nil {
+			return "", fmt.Errorf("invalid content type %q: %w", contentType, err)
+		}
+	}
 
-	data, err := yaml.Marshal(&cfg)
+	f, err := config.ReadOrEmpty()
 	if err != nil {
-		return fmt.Errorf("unable to marshal config: %w", err)
+		return "", err
+	}
+
+	if contentType == "" {
+		delete(f.ContentTypes, ext)
+	} else {
+		if f.ContentTypes == nil {
+			f.ContentTypes = map[string]string{}
+		}
+		f.ContentTypes[ext] = contentType
+	}
+
+	return ext, config.Write(f)
+}
+
+// migrateSecrets re-stores the secret key of the active profile, or of all
+// profiles, in store. Profiles without a secret key are skipped.
+func migrateSecrets(store string, all bool) (int, error) {
+	if !config.ValidStore(store) {
+		return 0, fmt.Errorf("invalid --store %q (use keyring, file or plain)", store)
 	}
 
-	err = os.WriteFile(configFile, data, 0600)
+	f, err := config.Read()
 	if err != nil {
-		return fmt.Errorf("unable to write config file: %w", err)
+		return 0, err
 	}
 
-	return nil
+	names := []string{config.ActiveProfile(f)}
+	if all {
+		names = f.ProfileNames()
+	}
+
+	migrated := 0
+	for _, name := range names {
+		p, ok := f.Profile(name)
+		if !ok {
+			return migrated, fmt.Errorf("profile %q: %w", name, config.ErrProfileNotFound)
+		}
+		if p.Store == store || (store == config.StorePlain && p.Store == "") {
+			continue
+		}
+
+		secret, err := config.LoadSecret(name, p)
+		if err != nil {
+			return migrated, err
+		}
+		if secret == "" {
+			continue
+		}
+
+		if err := config.StoreSecret(f, name, store, secret); err != nil {
+			return migrated, fmt.Errorf("profile %q: %w", name, err)
+		}
+		// Save after each profile so the file never points at a store that
+		// does not hold the key.
+		if err := config.Write(f); err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+
+	return migrated, nil
 }
 
 func showConfig() error {
-	_, configFile, err := getConfigPaths()
+	configFile, pathSource, err := config.ResolvePath()
 	if err != nil {
 		return err
 	}
 
-	data, err := os.ReadFile(configFile)
+	cfg, err := config.Resolve()
 	if err != nil {
-		if os.IsNotExist(err) {
-			fmt.Println("No configuration file found.")
-			fmt.Println("Use 'ut config set-secret <key>' to set up your UploadThing secret key.")
-			return nil
-		}
-		return fmt.Errorf("unable to read config file: %w", err)
+		return err
 	}
 
-	var cfg ConfigFile
-	err = yaml.Unmarshal(data, &cfg)
-	if err != nil {
-		return fmt.Errorf("unable to parse config file: %w", err)
+	if cfg.Profile == "" && cfg.Source == "" {
+		infof("No configuration file found at %s.\n", configFile)
+		infof("Use 'ut config init' to set up your UploadThing secret key.\n")
+		return nil
+	}
+
+	if cfg.Profile == "" {
+		configFile = ""
+	}
+
+	if !isTableOutput() {
+		view := ConfigView{
+			ConfigFile: configFile,
+			Profile:    cfg.Profile,
+			AppName:    cfg.AppName,
+			Regions:    strings.Join(cfg.Regions, ","),
+			Source:     cfg.Source,
+			Store:      cfg.Store,
+			APIURL:     cfg.APIURL,
+			FileHost:   cfg.FileHost,
+		}
+		if cfg.SecretKey != "" {
+			view.SecretKey = maskSecretKey(cfg.SecretKey)
+		}
+		return writeRecords(view)
 	}
 
 	fmt.Println("Current Configuration:")
-	fmt.Printf("  Config file: %s\n", configFile)
+	if configFile != "" {
+		fmt.Printf("  Config file: %s (from %s)\n", configFile, pathSource)
+		fmt.Printf("  Profile: %s\n", cfg.Profile)
+		if cfg.Store != "" {
+			fmt.Printf("  Secret Store: %s\n", cfg.Store)
+		}
+	}
 	if cfg.AppName != "" {
 		fmt.Printf("  App Name: %s\n", cfg.AppName)
 	}
+	if cfg.APIURL != "" {
+		fmt.Printf("  API URL: %s\n", cfg.APIURL)
+	}
+	if cfg.FileHost != "" {
+		fmt.Printf("  File Host: %s\n", cfg.FileHost)
+	}
+	if len(cfg.Regions) > 0 {
+		fmt.Printf("  Regions: %s\n", strings.Join(cfg.Regions, ", "))
+	}
 	if cfg.SecretKey != "" {
 		maskedKey := maskSecretKey(cfg.SecretKey)
-		fmt.Printf("  Secret Key: %s\n", maskedKey)
+		fmt.Printf("  Secret Key: %s (from %s)\n", maskedKey, cfg.Source)
 	} else {
 		fmt.Println("  Secret Key: (not set)")
 	}
@@ -137,28 +383,6 @@ func showConfig() error {
 	return nil
 }
 
-func getConfigPaths() (string, string, error) {
-	home, err := homedir.Dir()
-	if err != nil {
-		return "", "", fmt.Errorf("unable to find home directory: %w", err)
-	}
-
-	configDir := filepath.Join(home, ".ut-cli")
-	configFile := filepath.Join(configDir, "config.yml")
-
-	return configDir, configFile, nil
-}
-
-func ensureConfigDir(configDir string) error {
-	if _, err := os.Stat(configDir); os.IsNotExist(err) {
-		err = os.MkdirAll(configDir, 0700)
-		if err != nil {
-			return fmt.Errorf("unable to create config directory: %w", err)
-		}
-	}
-	return nil
-}
-
 func maskSecretKey(key string) string {
 	if len(key) <= 8 {
 		return "****"
@@ -166,11 +390,45 @@ func maskSecretKey(key string) string {
 	return key[:4] + "****" + key[len(key)-4:]
 }
 
-func readSecret() (string, error) {
+func readSecret(prompt string) (string, error) {
+	defer beginPrompt()()
+
+	fmt.Fprint(os.Stderr, prompt)
 	bytePassword, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
 	if err != nil {
 		return "", err
 	}
-	fmt.Println()
 	return string(bytePassword), nil
 }
+
+// readPassphrase prompts on stderr for the passphrase of the encrypted secret
+// file, asking twice when a new secret is being encrypted.
+func readPassphrase(confirm bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("a passphrase is required; set %s", config.PassphraseEnv)
+	}
+	defer beginPrompt()()
+
+	fmt.Fprint(os.Stderr, "Secret file passphrase: ")
+	pass, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if !confirm {
+		return string(pass), nil
+	}
+
+	fmt.Fprint(os.Stderr, "Confirm passphrase: ")
+	again, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if string(pass) != string(again) {
+		return "", fmt.Errorf("passphrases do not match")
+	}
+	return string(pass), nil
+}
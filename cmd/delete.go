@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/MhemedAbderrahmen/ut/config"
+	"github.com/MhemedAbderrahmen/ut/uploadthing"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	deleteByCustomID bool
+	skipConfirm      bool
+)
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete <fileKey> [fileKey2]...",
+	Short: "Delete one or more files from UploadThing",
+	Long: `Delete one or more files from UploadThing by file key or custom ID.
+
+Keys are read from stdin (one per line) when no arguments are given and
+stdin is piped or redirected, or when the only argument is "-".
+
+Examples:
+  ut delete abc123-example.jpg                  # Delete a single file
+  ut delete abc123-a.jpg abc123-b.jpg --yes     # Delete without confirmation
+  ut delete --custom-id avatar-42               # Delete by custom ID
+  ut delete --yes < stale-keys.txt              # Delete keys listed in a file`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && term.IsTerminal(int(os.Stdin.Fd())) {
+			return fmt.Errorf(`requires at least one file key, keys piped to stdin, or "-" to type them`)
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		failed, err := runDelete(cmd.Context(), args)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error deleting files: %v\n", err)
+			exit(cmd.Context(), 1)
+		}
+		if failed > 0 {
+			exit(cmd.Context(), 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(deleteCmd)
+
+	deleteCmd.Flags().BoolVar(&deleteByCustomID, "custom-id", false, "Treat arguments as custom IDs instead of file keys")
+	deleteCmd.Flags().BoolVarP(&skipConfirm, "yes", "y", false, "Delete without asking for confirmation")
+}
+
+type DeleteResult struct {
+	Key     string `json:"key" yaml:"key"`
+	Deleted bool   `json:"deleted" yaml:"deleted"`
+	Error   string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func (r DeleteResult) csvHeader() []string {
+	return []string{"key", "deleted", "error"}
+}
+
+func (r DeleteResult) csvRow() []string {
+	return []string{r.Key, strconv.FormatBool(r.Deleted), r.Error}
+}
+
+func runDelete(ctx context.Context, args []string) (int, error) {
+	fromStdin := len(args) == 0 || (len(args) == 1 && args[0] == "-")
+
+	keys := args
+	if fromStdin {
+		var err error
+		keys, err = readKeys(os.Stdin)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read keys from stdin: %w", err)
+		}
+	}
+	if len(keys) == 0 {
+		return 0, fmt.Errorf("no file keys given")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	client := newClient(cfg)
+
+	kind := "file key"
+	if deleteByCustomID {
+		kind = "custom ID"
+	}
+
+	if !skipConfirm {
+		if fromStdin {
+			return 0, fmt.Errorf("refusing to prompt for confirmation while reading keys from stdin; pass --yes")
+		}
+		if !confirm(fmt.Sprintf("Delete %d file(s) by %s? This cannot be undone.", len(keys), kind)) {
+			return 0, fmt.Errorf("delete cancelled by user")
+		}
+	}
+
+	rw := newRecordWriter(os.Stdout, outputFormat)
+	defer rw.Close()
+
+	deleted, failed := 0, 0
+	for i, key := range keys {
+		if ctx.Err() != nil {
+			infof("\n%d deleted, %d failed, %d not started.\n", deleted, failed, len(keys)-i)
+			return failed, context.Cause(ctx)
+		}
+
+		result := DeleteResult{Key: key, Deleted: true}
+		if err := deleteFile(ctx, client, key); err != nil {
+			result = DeleteResult{Key: key, Error: err.Error()}
+			failed++
+		} else {
+			deleted++
+		}
+
+		if !isTableOutput() {
+			if err := rw.Write(result); err != nil {
+				return failed, fmt.Errorf("failed to write output: %w", err)
+			}
+		} else if result.Deleted {
+			fmt.Printf("✓ %s deleted\n", key)
+		} else {
+			fmt.Fprintf(os.Stderr, "✗ %s: %s\n", key, result.Error)
+		}
+	}
+
+	infof("\n%d deleted, %d failed.\n", deleted, failed)
+	return failed, nil
+}
+
+func deleteFile(ctx context.Context, client *uploadthing.Client, key string) error {
+	reqBody := uploadthing.DeleteFilesRequest{FileKeys: []string{key}}
+	if deleteByCustomID {
+		reqBody = uploadthing.DeleteFilesRequest{CustomIDs: []string{key}}
+	}
+
+	deleteResp, err := client.DeleteFiles(ctx, reqBody)
+	if err != nil {
+		return err
+	}
+
+	if !deleteResp.Success || deleteResp.DeletedCount == 0 {
+		return uploadthing.ErrNotFound
+	}
+
+	return nil
+}
+
+func readKeys(r io.Reader) ([]string, error) {
+	var keys []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key := strings.TrimSpace(scanner.Text())
+		if key == "" || strings.HasPrefix(key, "#") {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
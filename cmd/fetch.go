@@ -1,11 +1,10 @@
 package cmd
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -13,37 +12,70 @@ import (
 	"strings"
 	"time"
 
-	"ut/config"
+	"github.com/MhemedAbderrahmen/ut/config"
+	"github.com/MhemedAbderrahmen/ut/uploadthing"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	outputPath     string
-	forceOverwrite bool
-	showProgress   bool
-	isPrivate      bool
+	outputPath      string
+	forceOverwrite  bool
+	showProgress    bool
+	isPrivate       bool
+	resumeDownload  bool
+	fetchByCustomID bool
 )
 
 var (
-	ErrAPIKeyInvalid = errors.New("invalid API key")
+	ErrAPIKeyInvalid = uploadthing.ErrUnauthorized
 )
 
 var downloadCmd = &cobra.Command{
-	Use:   "fetch <fileKey>",
-	Short: "Download a file from UploadThing",
-	Long: `Download a file from UploadThing using a file key.
+	Use:   "fetch <fileKey> [fileKey2]...",
+	Short: "Download files from UploadThing",
+	Long: `Download one or more files from UploadThing using file keys.
+
+With --custom-id, the arguments are custom IDs instead of file keys. They are
+looked up in the file list, so a secret key is needed even for public files.
+
+Several keys can be given as arguments, read from stdin ("-"), read from a
+file with --keys-file, or selected from the list API with --name-glob and
+--since. Multiple files are downloaded concurrently into the --output
+directory; files that share a name are saved with part of their key
+appended.
 	
 Examples:
   ut fetch abc123-example.jpg                    # Download to current directory
   ut fetch abc123-example.jpg -o myfile.jpg     # Download with custom name
   ut fetch abc123-example.jpg -o ./downloads/   # Download to specific directory
   ut fetch abc123-example.jpg --private         # Download private file (requires API key)
-  ut fetch abc123-example.jpg --progress        # Show download progress`,
-	Args: cobra.ExactArgs(1),
+  ut fetch abc123-example.jpg --progress        # Show download progress
+  ut fetch abc123-example.jpg --resume          # Resume an interrupted download
+  ut fetch key1 key2 key3 -o ./backup/          # Download several files
+  ut fetch --custom-id avatar-42                # Download by custom ID
+  ut fetch - -o ./backup/ < keys.txt            # Download keys read from stdin
+  ut fetch --name-glob '*.png' --since 7d -o ./backup/  # Download matching files`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && keysFile == "" && nameGlob == "" && sinceFilter == "" {
+			return fmt.Errorf("requires at least one file key, --keys-file, --name-glob or --since")
+		}
+		return nil
+	},
 	Run: func(cmd *cobra.Command, args []string) {
-		fileKey := args[0]
-		err := runDownload(fileKey)
+		if isBulkDownload(args) {
+			failed, err := runBulkDownload(cmd.Context(), args)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error downloading files: %v\n", err)
+				exit(cmd.Context(), 1)
+			}
+			if failed > 0 {
+				exit(cmd.Context(), 1)
+			}
+			return
+		}
+
+		result, err := runSingleDownload(cmd.Context(), args[0])
 		if err != nil {
 			if errors.Is(err, config.ErrConfigNotFound) {
 				fmt.Fprintln(os.Stderr, `API key is not configured.
@@ -53,9 +85,16 @@ Run 'ut config set-secret' before downloading private files.`)
 			} else {
 				fmt.Fprintf(os.Stderr, "Error downloading file: %v\n", err)
 			}
+			exit(cmd.Context(), 1)
+		}
+		if isTableOutput() {
+			fmt.Printf("Download complete: %s (%s)\n", result.Path, formatFileSize(result.Size))
+			return
+		}
+		if err := writeRecords(result); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Println("File downloaded successfully!")
 	},
 }
 
@@ -66,116 +105,149 @@ func init() {
 	downloadCmd.Flags().BoolVarP(&forceOverwrite, "force", "f", false, "Overwrite existing file without prompt")
 	downloadCmd.Flags().BoolVarP(&showProgress, "progress", "p", false, "Show download progress")
 	downloadCmd.Flags().BoolVar(&isPrivate, "private", false, "Download private file (requires API key)")
+	downloadCmd.Flags().BoolVar(&resumeDownload, "resume", false, "Download via a .part file and resume an interrupted download")
+	downloadCmd.Flags().BoolVar(&fetchByCustomID, "custom-id", false, "Treat arguments and keys read from stdin or --keys-file as custom IDs")
+	downloadCmd.Flags().StringVar(&keysFile, "keys-file", "", "Read file keys from a file, one per line")
+	downloadCmd.Flags().StringVar(&nameGlob, "name-glob", "", "Download all files whose name matches this glob")
+	downloadCmd.Flags().StringVar(&sinceFilter, "since", "", "Only download files uploaded after this date (2006-01-02, RFC 3339) or duration ago (24h, 7d)")
+	downloadCmd.Flags().IntVarP(&downloadConcurrency, "concurrency", "c", 4, "Number of files downloaded in parallel")
+}
+
+type DownloadResult struct {
+	Key   string `json:"key" yaml:"key"`
+	Path  string `json:"path,omitempty" yaml:"path,omitempty"`
+	Size  int64  `json:"size" yaml:"size"`
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
 }
 
-type FileAccessResponse struct {
-	URL string `json:"url"`
+func (r DownloadResult) csvHeader() []string {
+	return []string{"key", "path", "size", "error"}
 }
 
-func runDownload(fileKey string) error {
+func (r DownloadResult) csvRow() []string {
+	return []string{r.Key, r.Path, strconv.FormatInt(r.Size, 10), r.Error}
+}
+
+// runSingleDownload downloads the file given by a single argument, looking it
+// up first if it is a custom ID.
+func runSingleDownload(ctx context.Context, arg string) (*DownloadResult, error) {
+	target := downloadTarget{Key: arg}
+	if fetchByCustomID {
+		targets, err := resolveCustomIDs(ctx, []string{arg})
+		if err != nil {
+			return nil, err
+		}
+		target = targets[0]
+	}
+	return runDownload(ctx, target, false)
+}
+
+// runDownload downloads a single target. In bulk mode the file is placed
+// inside the --output directory, existing files are never prompted for and
+// progress output is disabled. If ctx is canceled mid-transfer, the partial
+// file is removed, or kept as a .part file for --resume.
+func runDownload(ctx context.Context, target downloadTarget, bulk bool) (*DownloadResult, error) {
+	fileKey := target.Key
 	if strings.TrimSpace(fileKey) == "" {
-		return fmt.Errorf("file key cannot be empty")
+		return nil, fmt.Errorf("file key cannot be empty")
 	}
 
 	var fileURL string
-	var filename string
+	filename := target.Name
+	if filename == "" {
+		filename = extractFilenameFromKey(fileKey)
+	}
 
+	settings, err := config.ResolveSettings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	client := newClient(settings)
 	if isPrivate {
-		signedURL, err := getSignedURL(fileKey)
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config (API key required for private files): %w", err)
+		}
+		client = newClient(cfg)
+
+		signedURL, err := client.RequestFileAccess(ctx, fileKey)
 		if err != nil {
-			return fmt.Errorf("failed to get signed URL for private file: %w", err)
+			return nil, fmt.Errorf("failed to get signed URL for private file: %w", err)
 		}
 		fileURL = signedURL
-		filename = extractFilenameFromKey(fileKey)
 	} else {
-		fileURL = "https://utfs.io/f/" + fileKey
-		filename = extractFilenameFromKey(fileKey)
+		fileURL, err = client.FileURL(fileKey)
+		if err != nil {
+			return nil, fmt.Errorf("%w (set it with 'ut config profile add <name> --app-name <appId>' or UPLOADTHING_TOKEN)", err)
+		}
 	}
 
-	_, err := url.ParseRequestURI(fileURL)
+	_, err = url.ParseRequestURI(fileURL)
 	if err != nil {
-		return fmt.Errorf("invalid URL generated: %w", err)
+		return nil, fmt.Errorf("invalid URL generated: %w", err)
 	}
 
-	outputFilePath, err := determineOutputPath(filename)
+	var outputFilePath string
+	if bulk {
+		outputFilePath, err = bulkOutputPath(filename)
+	} else {
+		outputFilePath, err = determineOutputPath(filename)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to determine output path: %w", err)
+		return nil, fmt.Errorf("failed to determine output path: %w", err)
 	}
 
+	progress := showProgress && !bulk
+
 	if !forceOverwrite {
 		if _, err := os.Stat(outputFilePath); err == nil {
-			fmt.Printf("File '%s' already exists. Overwrite? (y/N): ", outputFilePath)
-			var response string
-			fmt.Scanln(&response)
-			if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
-				return fmt.Errorf("download cancelled by user")
+			if bulk {
+				return nil, fmt.Errorf("file '%s' already exists (use --force to overwrite)", outputFilePath)
+			}
+			if !confirm(fmt.Sprintf("File '%s' already exists. Overwrite?", outputFilePath)) {
+				return nil, fmt.Errorf("download cancelled by user")
 			}
 		}
 	}
 
-	outputFile, err := os.Create(outputFilePath)
-	if err != nil {
-		return fmt.Errorf("unable to create output file %s: %w", outputFilePath, err)
-	}
-	defer outputFile.Close()
-
-	fmt.Printf("Downloading %s...\n", filename)
+	infof("Downloading %s...\n", filename)
 
-	if showProgress {
-		err = downloadWithProgress(fileURL, outputFile)
-	} else {
-		err = downloadFile(fileURL, outputFile)
-	}
-
-	if err != nil {
-		os.Remove(outputFilePath)
-		return fmt.Errorf("download failed: %w", err)
+	if resumeDownload {
+		size, err := resumableDownload(ctx, client, fileURL, outputFilePath, progress)
+		if err != nil {
+			return nil, fmt.Errorf("download failed: %w", err)
+		}
+		return &DownloadResult{
+			Key:  fileKey,
+			Path: outputFilePath,
+			Size: size,
+		}, nil
 	}
 
-	fileInfo, _ := outputFile.Stat()
-	fmt.Printf("Download complete: %s (%s)\n", outputFilePath, formatFileSize(fileInfo.Size()))
-
-	return nil
-}
-
-func getSignedURL(fileKey string) (string, error) {
-	cfg, err := config.LoadConfig()
+	outputFile, err := os.Create(outputFilePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to load config (API key required for private files): %w", err)
+		return nil, fmt.Errorf("unable to create output file %s: %w", outputFilePath, err)
 	}
+	defer outputFile.Close()
 
-	apiURL := "https://api.uploadthing.com/v6/requestFileAccess"
-	reqBody := fmt.Sprintf(`{"fileKey": "%s"}`, fileKey)
-
-	req, err := http.NewRequest(http.MethodPost, apiURL, strings.NewReader(reqBody))
+	err = downloadFile(ctx, client, fileURL, outputFile, progress)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		outputFile.Close()
+		os.Remove(outputFilePath)
+		return nil, fmt.Errorf("download failed: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Uploadthing-Api-Key", cfg.SecretKey)
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	fileInfo, err := outputFile.Stat()
 	if err != nil {
-		return "", fmt.Errorf("API request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
-			return "", fmt.Errorf("request unauthorized: %w", ErrAPIKeyInvalid)
-		}
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API request failed: status %d, response: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("unable to stat output file: %w", err)
 	}
 
-	var accessResp FileAccessResponse
-	if err := json.NewDecoder(resp.Body).Decode(&accessResp); err != nil {
-		return "", fmt.Errorf("failed to parse API response: %w", err)
-	}
-
-	return accessResp.URL, nil
+	return &DownloadResult{
+		Key:  fileKey,
+		Path: outputFilePath,
+		Size: fileInfo.Size(),
+	}, nil
 }
 
 func extractFilenameFromKey(fileKey string) string {
@@ -212,55 +284,32 @@ func determineOutputPath(filename string) (string, error) {
 	return outputPath, nil
 }
 
-func downloadFile(fileURL string, outputFile *os.File) error {
-	resp, err := http.Get(fileURL)
-	if err != nil {
-		return fmt.Errorf("HTTP request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP error: %s", resp.Status)
-	}
-
-	_, err = io.Copy(outputFile, resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
-	}
-
-	return nil
-}
-
-func downloadWithProgress(fileURL string, outputFile *os.File) error {
-	resp, err := http.Get(fileURL)
+// downloadFile copies fileURL into outputFile, printing progress when asked.
+func downloadFile(ctx context.Context, client *uploadthing.Client, fileURL string, outputFile *os.File, progress bool) error {
+	body, fileSize, err := client.Download(ctx, fileURL)
 	if err != nil {
-		return fmt.Errorf("HTTP request failed: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP error: %s", resp.Status)
-	}
-
-	var fileSize int64
-	if contentLength := resp.Header.Get("Content-Length"); contentLength != "" {
-		if size, err := strconv.ParseInt(contentLength, 10, 64); err == nil {
-			fileSize = size
+	defer body.Close()
+
+	var src io.Reader = body
+	if progress {
+		progressWriter := &ProgressWriter{
+			Total:      max(fileSize, 0),
+			Downloaded: 0,
+			StartTime:  time.Now(),
 		}
+		src = io.TeeReader(body, progressWriter)
 	}
 
-	progressWriter := &ProgressWriter{
-		Total:      fileSize,
-		Downloaded: 0,
-		StartTime:  time.Now(),
+	_, err = io.Copy(outputFile, src)
+	if progress {
+		infof("\n")
 	}
-
-	_, err = io.Copy(outputFile, io.TeeReader(resp.Body, progressWriter))
 	if err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
 
-	fmt.Println()
 	return nil
 }
 
@@ -287,13 +336,13 @@ func (pw *ProgressWriter) printProgress() {
 
 	if pw.Total > 0 {
 		percentage := float64(pw.Downloaded) / float64(pw.Total) * 100
-		fmt.Printf("\rProgress: %.1f%% (%s/%s) - %.2f KB/s",
+		infof("\rProgress: %.1f%% (%s/%s) - %.2f KB/s",
 			percentage,
 			formatFileSize(pw.Downloaded),
 			formatFileSize(pw.Total),
 			speed/1024)
 	} else {
-		fmt.Printf("\rDownloaded: %s - %.2f KB/s",
+		infof("\rDownloaded: %s - %.2f KB/s",
 			formatFileSize(pw.Downloaded),
 			speed/1024)
 	}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/MhemedAbderrahmen/ut/config"
+	"github.com/MhemedAbderrahmen/ut/uploadthing"
+
+	"github.com/spf13/cobra"
+)
+
+var infoByCustomID bool
+
+var infoCmd = &cobra.Command{
+	Use:   "info <fileKey> [fileKey2]...",
+	Short: "Show detailed information about files",
+	Long: `Show the name, size, key, custom ID, upload time and status of files from the
+file list, together with their ACL, Content-Type and URL from the file host.
+
+The ACL is determined by requesting the file's public URL: files that cannot be
+read through it are private, and a signed URL is shown for them instead.
+
+Examples:
+  ut info abc123-example.jpg                 # Show a file's details
+  ut info --custom-id avatar-42              # Look up a file by custom ID
+  ut info abc123-example.jpg --format json   # Machine-readable output`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := showInfo(cmd.Context(), args); err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting file info: %v\n", err)
+			exit(cmd.Context(), 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+
+	infoCmd.Flags().BoolVar(&infoByCustomID, "custom-id", false, "Treat arguments as custom IDs instead of file keys")
+}
+
+type FileDetails struct {
+	ID                 string    `json:"id" yaml:"id"`
+	Name               string    `json:"name" yaml:"name"`
+	Key                string    `json:"key" yaml:"key"`
+	CustomID           string    `json:"customId,omitempty" yaml:"customId,omitempty"`
+	Size               int64     `json:"size" yaml:"size"`
+	UploadedAt         time.Time `json:"uploadedAt" yaml:"uploadedAt"`
+	Status             string    `json:"status,omitempty" yaml:"status,omitempty"`
+	ACL                string    `json:"acl,omitempty" yaml:"acl,omitempty"`
+	ContentType        string    `json:"contentType,omitempty" yaml:"contentType,omitempty"`
+	ContentDisposition string    `json:"contentDisposition,omitempty" yaml:"contentDisposition,omitempty"`
+	ETag               string    `json:"etag,omitempty" yaml:"etag,omitempty"`
+	URL                string    `json:"url,omitempty" yaml:"url,omitempty"`
+	SignedURL          string    `json:"signedUrl,omitempty" yaml:"signedUrl,omitempty"`
+}
+
+func (d FileDetails) csvHeader() []string {
+	return []string{"id", "name", "key", "customId", "size", "uploadedAt", "status", "acl", "contentType", "contentDisposition", "etag", "url", "signedUrl"}
+}
+
+func (d FileDetails) csvRow() []string {
+	return []string{
+		d.ID, d.Name, d.Key, d.CustomID, strconv.FormatInt(d.Size, 10), d.UploadedAt.Format(time.RFC3339),
+		d.Status, d.ACL, d.ContentType, d.ContentDisposition, d.ETag, d.URL, d.SignedURL,
+	}
+}
+
+func showInfo(ctx context.Context, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	client := newClient(cfg)
+
+	kind := "key"
+	var files []uploadthing.FileInfo
+	var missing []string
+	if infoByCustomID {
+		kind = "custom ID"
+		files, err = findFiles(ctx, client, nil, args)
+		missing = missingFiles(files, args, true)
+	} else {
+		files, err = findFiles(ctx, client, args, nil)
+		missing = missingFiles(files, args, false)
+	}
+	if err != nil {
+		return err
+	}
+	if len(missing) == len(args) {
+		return fmt.Errorf("no file with %s %s: %w", kind, missing[0], uploadthing.ErrNotFound)
+	}
+
+	details := make([]FileDetails, 0, len(files))
+	for _, file := range files {
+		details = append(details, fileDetails(ctx, client, file))
+	}
+
+	if !isTableOutput() {
+		records := make([]record, 0, len(details))
+		for _, d := range details {
+			records = append(records, d)
+		}
+		if err := writeRecords(records...); err != nil {
+			return err
+		}
+	} else {
+		for i, d := range details {
+			if i > 0 {
+				fmt.Println()
+			}
+			printFileDetails(d)
+		}
+	}
+
+	if len(missing) > 0 {
+		for _, m := range missing {
+			fmt.Fprintf(os.Stderr, "No file with %s %s\n", kind, m)
+		}
+		return fmt.Errorf("%d of %d files not found", len(missing), len(args))
+	}
+	return nil
+}
+
+// fileDetails adds what the file host reports to a listed file. A file whose
+// public URL is refused is private and is looked at through a signed URL.
+// Failures to reach the file host are reported but leave the listed details.
+func fileDetails(ctx context.Context, client *uploadthing.Client, file uploadthing.FileInfo) FileDetails {
+	d := FileDetails{
+		ID:         file.ID,
+		Name:       file.Name,
+		Key:        file.FileKey,
+		CustomID:   file.CustomID,
+		Size:       file.Size,
+		UploadedAt: file.UploadedTime(),
+		Status:     file.Status,
+	}
+
+	publicURL, err := client.FileURL(file.FileKey)
+	if err != nil {
+		infof("Cannot check %s on the file host: %v\n", file.FileKey, err)
+		return d
+	}
+	d.URL = publicURL
+
+	headers, err := client.Head(ctx, publicURL)
+	switch {
+	case err == nil:
+		d.ACL = uploadthing.ACLPublicRead
+	case errors.Is(err, uploadthing.ErrUnauthorized):
+		d.ACL = uploadthing.ACLPrivate
+		d.URL = ""
+		d.SignedURL, err = client.RequestFileAccess(ctx, file.FileKey)
+		if err != nil {
+			infof("Cannot get a signed URL for %s: %v\n", file.FileKey, err)
+			return d
+		}
+		headers, err = client.Head(ctx, d.SignedURL)
+		if err != nil {
+			infof("Cannot read headers of %s: %v\n", file.FileKey, err)
+			return d
+		}
+	default:
+		infof("Cannot read headers of %s: %v\n", file.FileKey, err)
+		return d
+	}
+
+	d.ContentType = headers.ContentType
+	d.ContentDisposition = headers.ContentDisposition
+	d.ETag = headers.ETag
+	return d
+}
+
+func printFileDetails(d FileDetails) {
+	fmt.Printf("📄 %s\n", d.Name)
+	fmt.Printf("   File Key:     %s\n", d.Key)
+	if d.CustomID != "" {
+		fmt.Printf("   Custom ID:    %s\n", d.CustomID)
+	}
+	fmt.Printf("   ID:           %s\n", d.ID)
+	fmt.Printf("   Size:         %s (%d bytes)\n", formatFileSize(d.Size), d.Size)
+	fmt.Printf("   Uploaded:     %s\n", d.UploadedAt.Format("2006-01-02 15:04:05"))
+	if d.Status != "" {
+		fmt.Printf("   Status:       %s\n", d.Status)
+	}
+	if d.ACL != "" {
+		fmt.Printf("   ACL:          %s\n", d.ACL)
+	}
+	if d.ContentType != "" {
+		fmt.Printf("   Content-Type: %s\n", d.ContentType)
+	}
+	if d.ContentDisposition != "" {
+		fmt.Printf("   Disposition:  %s\n", d.ContentDisposition)
+	}
+	if d.ETag != "" {
+		fmt.Printf("   ETag:         %s\n", d.ETag)
+	}
+	if d.URL != "" {
+		fmt.Printf("   URL:          %s\n", d.URL)
+	}
+	if d.SignedURL != "" {
+		fmt.Printf("   Signed URL:   %s\n", d.SignedURL)
+	}
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/MhemedAbderrahmen/ut/config"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Set up credentials interactively",
+	Long: `Prompt for your UploadThing secret key and app name, check the key against
+the UploadThing API and save them to the active profile.
+
+Nothing is written unless the key is accepted by UploadThing.
+
+Examples:
+  ut config init                      # Configure the active profile
+  ut --profile staging config init    # Configure another profile
+  ut config init --store keyring      # Keep the key in the OS keyring`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := runInit(cmd.Context(), secretStore)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(cmd.Context(), 1)
+		}
+	},
+}
+
+func init() {
+	configCmd.AddCommand(initCmd)
+
+	initCmd.Flags().StringVar(&secretStore, "store", "", "Where to keep the secret key (keyring|file|plain)")
+}
+
+func runInit(ctx context.Context, store string) error {
+	if store != "" && !config.ValidStore(store) {
+		return fmt.Errorf("invalid --store %q (use keyring, file or plain)", store)
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return errors.New("ut config init needs a terminal; use 'ut config set-secret' in scripts")
+	}
+
+	f, err := config.ReadOrEmpty()
+	if err != nil {
+		return err
+	}
+	name := config.ActiveProfile(f)
+	p, _ := f.Profile(name)
+
+	infof("Configuring profile '%s'.\n", name)
+	infof("Find your secret key in the UploadThing dashboard under API Keys.\n\n")
+
+	secretKey, err := readSecret("Secret key: ")
+	if err != nil {
+		return fmt.Errorf("failed to read secret key: %w", err)
+	}
+	secretKey = strings.TrimSpace(secretKey)
+	if secretKey == "" {
+		return errors.New("secret key cannot be empty")
+	}
+	if !strings.HasPrefix(secretKey, "sk_") {
+		return errors.New("secret keys start with sk_")
+	}
+
+	prompt := "App name (optional): "
+	if p.AppName != "" {
+		prompt = fmt.Sprintf("App name [%s]: ", p.AppName)
+	}
+	appName, err := readLine(os.Stdin, prompt)
+	if err != nil {
+		return fmt.Errorf("failed to read app name: %w", err)
+	}
+	if appName != "" {
+		p.AppName = appName
+	}
+
+	infof("Checking the secret key with UploadThing...\n")
+	if err := validateSecretKey(ctx, name, p, secretKey); err != nil {
+		if errors.Is(err, ErrAPIKeyInvalid) {
+			return errors.New("UploadThing rejected the secret key; nothing was saved")
+		}
+		return fmt.Errorf("unable to verify the secret key, nothing was saved: %w", err)
+	}
+
+	f.SetProfile(name, p)
+	if err := config.StoreSecret(f, name, store, secretKey); err != nil {
+		return err
+	}
+	if err := config.Write(f); err != nil {
+		return err
+	}
+
+	configFile, err := config.Path()
+	if err != nil {
+		return err
+	}
+	infof("✓ Secret key verified and saved to %s\n", configFile)
+	return nil
+}
+
+// validateSecretKey makes an authenticated call that has no side effects so a
+// bad key is caught before it is saved. The call goes to the API URL profile
+// p will use, so keys for a mock or self-hosted endpoint can be checked.
+func validateSecretKey(ctx context.Context, name string, p config.Profile, secretKey string) error {
+	cfg, err := config.ProfileSettings(name, p)
+	if err != nil {
+		return err
+	}
+	cfg.SecretKey = secretKey
+	_, err = newClient(cfg).GetUsageInfo(ctx)
+	return err
+}
+
+// readSecretArg returns the secret key given on the command line, or reads it
+// from stdin when no argument or "-" is given, so the key stays out of the
+// shell history. A terminal gets a hidden prompt.
+func readSecretArg(args []string) (string, error) {
+	if len(args) == 1 && args[0] != "-" {
+		return args[0], nil
+	}
+
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return readSecret("Secret key: ")
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret key from stdin: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func readLine(r io.Reader, prompt string) (string, error) {
+	defer beginPrompt()()
+
+	fmt.Fprint(os.Stderr, prompt)
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MhemedAbderrahmen/ut/config"
+)
+
+func TestValidateSecretKeyUsesConfiguredAPIURL(t *testing.T) {
+	var gotKey string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v6/getUsageInfo" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		gotKey = r.Header.Get("X-Uploadthing-Api-Key")
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	for _, env := range []string{config.TokenEnv, config.APIURLEnv, config.FileHostEnv} {
+		t.Setenv(env, "")
+	}
+
+	tests := []struct {
+		name    string
+		profile config.Profile
+		env     string
+		flag    string
+	}{
+		{"profile", config.Profile{APIURL: srv.URL}, "", ""},
+		{"environment", config.Profile{APIURL: "http://profile.invalid"}, srv.URL, ""},
+		{"flag", config.Profile{APIURL: "http://profile.invalid"}, "http://env.invalid", srv.URL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotKey = ""
+			t.Setenv(config.APIURLEnv, tt.env)
+			config.SetEndpointOverride(tt.flag, "")
+			defer config.SetEndpointOverride("", "")
+
+			if err := validateSecretKey(context.Background(), "staging", tt.profile, "sk_test_key"); err != nil {
+				t.Fatalf("validateSecretKey: %v", err)
+			}
+			if gotKey != "sk_test_key" {
+				t.Errorf("API key header = %q, want sk_test_key", gotKey)
+			}
+		})
+	}
+}
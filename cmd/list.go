@@ -1,45 +1,55 @@
 package cmd
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
-	"time"
+	"strconv"
 
-	"ut/config"
+	"github.com/MhemedAbderrahmen/ut/config"
+	"github.com/MhemedAbderrahmen/ut/uploadthing"
 
 	"github.com/spf13/cobra"
 )
 
-type FilesResponse struct {
-	HasMore bool       `json:"hasMore"`
-	Files   []FileInfo `json:"files"`
+const defaultPageSize = 500
+
+// FileInfo is the output record of a file listed by the API.
+type FileInfo uploadthing.FileInfo
+
+func (f FileInfo) csvHeader() []string {
+	return []string{"id", "name", "size", "key", "customId", "uploadedAt"}
 }
 
-type FileInfo struct {
-	ID         string `json:"id"`
-	Name       string `json:"name"`
-	Size       int64  `json:"size"`
-	FileKey    string `json:"key"`
-	UploadedAt int64  `json:"uploadedAt"`
+func (f FileInfo) csvRow() []string {
+	return []string{f.ID, f.Name, strconv.FormatInt(f.Size, 10), f.FileKey, f.CustomID, strconv.FormatInt(f.UploadedAt, 10)}
 }
 
 var (
-	verbose bool
+	verbose       bool
+	listLimit     int
+	listOffset    int
+	listAll       bool
+	listKeys      []string
+	listCustomIDs []string
 )
 
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all uploaded files",
-	Long:  `List all files uploaded to your UploadThing storage.`,
+	Long: `List all files uploaded to your UploadThing storage.
+
+Examples:
+  ut list                          # List the first page of files
+  ut list --limit 50 --offset 100  # List 50 files starting at offset 100
+  ut list --all                    # Walk every page until no files remain
+  ut list --custom-id avatar-42    # Look up a file by its custom ID
+  ut list --key abc123-a.jpg -v    # Show the details of a file by key`,
 	Run: func(cmd *cobra.Command, args []string) {
-		err := listFiles()
+		err := listFiles(cmd.Context())
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error listing files: %v\n", err)
-			os.Exit(1)
+			exit(cmd.Context(), 1)
 		}
 	},
 }
@@ -47,77 +57,211 @@ var listCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(listCmd)
 	listCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show detailed file information")
+	listCmd.Flags().IntVar(&listLimit, "limit", 0, "Maximum number of files per page (default: server default)")
+	listCmd.Flags().IntVar(&listOffset, "offset", 0, "Number of files to skip")
+	listCmd.Flags().BoolVar(&listAll, "all", false, "Fetch every page until no more files are available")
+	listCmd.Flags().StringArrayVar(&listKeys, "key", nil, "Only list the file with this key (repeatable)")
+	listCmd.Flags().StringArrayVar(&listCustomIDs, "custom-id", nil, "Only list the file with this custom ID (repeatable)")
 }
 
-func listFiles() error {
+func listFiles(ctx context.Context) error {
+	if listLimit < 0 {
+		return fmt.Errorf("--limit cannot be negative")
+	}
+	if listOffset < 0 {
+		return fmt.Errorf("--offset cannot be negative")
+	}
+
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
-	emptyBody := make(map[string]interface{})
-	jsonBody, err := json.Marshal(emptyBody)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request body: %w", err)
+	if len(listKeys) > 0 || len(listCustomIDs) > 0 {
+		return listSelectedFiles(ctx, newClient(cfg))
 	}
 
-	apiURL := "https://api.uploadthing.com/v6/listFiles"
-	req, err := http.NewRequest(http.MethodPost, apiURL, bytes.NewReader(jsonBody))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+	pageSize := listLimit
+	if listAll && pageSize == 0 {
+		pageSize = defaultPageSize
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Uploadthing-Api-Key", cfg.SecretKey)
+	it := newClient(cfg).Files(pageSize, listOffset)
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("API request failed: %w", err)
+	if !isTableOutput() {
+		return streamFiles(ctx, it)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API request failed: status %d, response: %s", resp.StatusCode, string(body))
+	if !listAll {
+		files, err := it.Next(ctx)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			fmt.Println("No files found.")
+			return nil
+		}
+
+		fmt.Printf("Found %d files:\n\n", len(files))
+		printFiles(files)
+
+		if it.HasNext() {
+			infof("\n... more files available (use --offset %d or --all)\n", it.Offset())
+		}
+		return nil
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
+	total := 0
+	for it.HasNext() {
+		files, err := it.Next(ctx)
+		if err != nil {
+			return fmt.Errorf("failed after %d files: %w", total, err)
+		}
+		printFiles(files)
+		total += len(files)
+	}
+
+	if total == 0 {
+		fmt.Println("No files found.")
+		return nil
 	}
 
-	var filesResp FilesResponse
-	err = json.Unmarshal(body, &filesResp)
+	fmt.Printf("\nListed %d files.\n", total)
+	return nil
+}
+
+// listSelectedFiles prints the files given by --key and --custom-id. Files
+// that do not exist are reported on stderr.
+func listSelectedFiles(ctx context.Context, client *uploadthing.Client) error {
+	files, err := findFiles(ctx, client, listKeys, listCustomIDs)
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal response: %w", err)
+		return err
+	}
+	for _, key := range missingFiles(files, listKeys, false) {
+		infof("No file with key %s\n", key)
+	}
+	for _, id := range missingFiles(files, listCustomIDs, true) {
+		infof("No file with custom ID %s\n", id)
 	}
 
-	if len(filesResp.Files) == 0 {
+	if !isTableOutput() {
+		records := make([]record, 0, len(files))
+		for _, file := range files {
+			records = append(records, FileInfo(file))
+		}
+		return writeRecords(records...)
+	}
+	if len(files) == 0 {
 		fmt.Println("No files found.")
 		return nil
 	}
+	printFiles(files)
+	return nil
+}
+
+// findFiles walks the file list until every key and custom ID has been found
+// and returns the matching files in list order.
+func findFiles(ctx context.Context, client *uploadthing.Client, keys, customIDs []string) ([]uploadthing.FileInfo, error) {
+	wantKeys := map[string]bool{}
+	for _, key := range keys {
+		wantKeys[key] = true
+	}
+	wantIDs := map[string]bool{}
+	for _, id := range customIDs {
+		wantIDs[id] = true
+	}
+	remaining := len(wantKeys) + len(wantIDs)
+
+	var found []uploadthing.FileInfo
+	it := client.Files(defaultPageSize, 0)
+	for it.HasNext() && remaining > 0 {
+		files, err := it.Next(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files: %w", err)
+		}
+		for _, file := range files {
+			matched := false
+			if wantKeys[file.FileKey] {
+				delete(wantKeys, file.FileKey)
+				remaining--
+				matched = true
+			}
+			if file.CustomID != "" && wantIDs[file.CustomID] {
+				delete(wantIDs, file.CustomID)
+				remaining--
+				matched = true
+			}
+			if matched {
+				found = append(found, file)
+			}
+		}
+	}
+	return found, nil
+}
+
+// missingFiles returns the keys, or custom IDs if byCustomID is set, that are
+// not among files.
+func missingFiles(files []uploadthing.FileInfo, wanted []string, byCustomID bool) []string {
+	have := map[string]bool{}
+	for _, file := range files {
+		if byCustomID {
+			have[file.CustomID] = true
+		} else {
+			have[file.FileKey] = true
+		}
+	}
 
-	fmt.Printf("Found %d files:\n\n", len(filesResp.Files))
+	var missing []string
+	for _, w := range wanted {
+		if !have[w] {
+			missing = append(missing, w)
+			have[w] = true
+		}
+	}
+	return missing
+}
 
+func streamFiles(ctx context.Context, it *uploadthing.FileIterator) error {
+	rw := newRecordWriter(os.Stdout, outputFormat)
+	defer rw.Close()
+
+	for it.HasNext() {
+		files, err := it.Next(ctx)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			if err := rw.Write(FileInfo(file)); err != nil {
+				return fmt.Errorf("failed to write output: %w", err)
+			}
+		}
+		if !listAll {
+			break
+		}
+	}
+
+	if !listAll && it.HasNext() {
+		infof("more files available (use --offset %d or --all)\n", it.Offset())
+	}
+	return nil
+}
+
+func printFiles(files []uploadthing.FileInfo) {
 	if verbose {
-		for _, file := range filesResp.Files {
-			uploadedTime := time.Unix(file.UploadedAt, 0)
+		for _, file := range files {
+			uploadedTime := file.UploadedTime()
 			fmt.Printf("📄 %s\n", file.Name)
 			fmt.Printf("   File Key: %s\n", file.FileKey)
+			if file.CustomID != "" {
+				fmt.Printf("   Custom ID: %s\n", file.CustomID)
+			}
 			fmt.Printf("   Size: %s\n", formatFileSize(file.Size))
 			fmt.Printf("   Uploaded: %s\n", uploadedTime.Format("2006-01-02 15:04:05"))
 			fmt.Printf("   ID: %s\n\n", file.ID)
 		}
 	} else {
-		for _, file := range filesResp.Files {
+		for _, file := range files {
 			fmt.Printf("📄 %-30s %s\n", file.Name, file.FileKey)
 		}
 	}
-
-	if filesResp.HasMore {
-		fmt.Println("\n... more files available (pagination not implemented)")
-	}
-
-	return nil
 }
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	formatTable  = "table"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+	formatYAML   = "yaml"
+	formatCSV    = "csv"
+)
+
+var outputFormats = []string{formatTable, formatJSON, formatNDJSON, formatYAML, formatCSV}
+
+var outputFormat string
+
+// record is a single result row that can be rendered in every machine-readable format.
+type record interface {
+	csvHeader() []string
+	csvRow() []string
+}
+
+func validateOutputFormat() error {
+	outputFormat = strings.ToLower(outputFormat)
+	for _, f := range outputFormats {
+		if outputFormat == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid output format %q (valid: %s)", outputFormat, strings.Join(outputFormats, ", "))
+}
+
+func isTableOutput() bool {
+	return outputFormat == "" || outputFormat == formatTable
+}
+
+// infof prints human-oriented progress and status messages. They always go to
+// stderr so that stdout only carries command results.
+func infof(format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, a...)
+}
+
+// recordWriter streams records to w in the selected machine-readable format.
+// Records can be written in several batches; Close must be called once at the
+// end to terminate formats that need it.
+type recordWriter struct {
+	w      io.Writer
+	format string
+	count  int
+	csv    *csv.Writer
+}
+
+func newRecordWriter(w io.Writer, format string) *recordWriter {
+	rw := &recordWriter{w: w, format: format}
+	if format == formatCSV {
+		rw.csv = csv.NewWriter(w)
+	}
+	return rw
+}
+
+func (rw *recordWriter) Write(records ...record) error {
+	for _, rec := range records {
+		if err := rw.writeOne(rec); err != nil {
+			return err
+		}
+		rw.count++
+	}
+	if rw.csv != nil {
+		rw.csv.Flush()
+		return rw.csv.Error()
+	}
+	return nil
+}
+
+func (rw *recordWriter) writeOne(rec record) error {
+	switch rw.format {
+	case formatJSON:
+		data, err := json.MarshalIndent(rec, "  ", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+		sep := ",\n  "
+		if rw.count == 0 {
+			sep = "[\n  "
+		}
+		_, err = fmt.Fprintf(rw.w, "%s%s", sep, data)
+		return err
+	case formatNDJSON:
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+		_, err = fmt.Fprintf(rw.w, "%s\n", data)
+		return err
+	case formatYAML:
+		data, err := yaml.Marshal([]record{rec})
+		if err != nil {
+			return fmt.Errorf("failed to encode YAML: %w", err)
+		}
+		_, err = rw.w.Write(data)
+		return err
+	case formatCSV:
+		if rw.count == 0 {
+			if err := rw.csv.Write(rec.csvHeader()); err != nil {
+				return err
+			}
+		}
+		return rw.csv.Write(rec.csvRow())
+	default:
+		return fmt.Errorf("output format %q does not support records", rw.format)
+	}
+}
+
+func (rw *recordWriter) Close() error {
+	switch rw.format {
+	case formatJSON:
+		if rw.count == 0 {
+			_, err := fmt.Fprintln(rw.w, "[]")
+			return err
+		}
+		_, err := fmt.Fprintln(rw.w, "\n]")
+		return err
+	case formatYAML:
+		if rw.count == 0 {
+			_, err := fmt.Fprintln(rw.w, "[]")
+			return err
+		}
+	}
+	return nil
+}
+
+// writeRecords renders a complete set of records to stdout.
+func writeRecords(records ...record) error {
+	rw := newRecordWriter(os.Stdout, outputFormat)
+	if err := rw.Write(records...); err != nil {
+		return err
+	}
+	return rw.Close()
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/MhemedAbderrahmen/ut/config"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	profileSecretKey string
+	profileAppName   string
+	profileStore     string
+	profileAPIURL    string
+	profileFileHost  string
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage named configuration profiles",
+	Long: `Manage named profiles for working with several UploadThing apps.
+
+The active profile is chosen by the global --profile flag, then the
+UT_PROFILE environment variable, then 'ut config profile use'.`,
+}
+
+var profileAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add or update a profile",
+	Long: `Add a profile, or update the settings of an existing one.
+
+Examples:
+  ut config profile add staging --secret sk_live_xxx --app-name my-app-staging
+  ut config profile add production --secret sk_live_yyy --store keyring
+  ut config profile add mock --secret sk_test --api-url http://localhost:8080
+  ut config profile add ufs --app-name abc123 --file-host 'https://{appId}.ufs.sh'`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := addProfile(args[0], cmd.Flags())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error adding profile: %v\n", err)
+			os.Exit(1)
+		}
+		infof("Profile '%s' saved.\n", args[0])
+	},
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Switch the active profile",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := useProfile(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error switching profile: %v\n", err)
+			os.Exit(1)
+		}
+		infof("Now using profile '%s'.\n", args[0])
+	},
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configured profiles",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := listProfiles()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing profiles: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var profileRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a profile",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := removeProfile(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error removing profile: %v\n", err)
+			os.Exit(1)
+		}
+		infof("Profile '%s' removed.\n", args[0])
+	},
+}
+
+func init() {
+	configCmd.AddCommand(profileCmd)
+	profileCmd.AddCommand(profileAddCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileRemoveCmd)
+
+	profileAddCmd.Flags().StringVar(&profileSecretKey, "secret", "", "Secret key for the profile")
+	profileAddCmd.Flags().StringVar(&profileAppName, "app-name", "", "App name for the profile")
+	profileAddCmd.Flags().StringVar(&profileStore, "store", "", "Where to keep the secret key (keyring|file|plain)")
+	profileAddCmd.Flags().StringVar(&profileAPIURL, "api-url", "", "API base URL for the profile (empty for the default)")
+	profileAddCmd.Flags().StringVar(&profileFileHost, "file-host", "", "File host for the profile, may contain {appId} (empty for the default)")
+}
+
+type ProfileView struct {
+	Name      string `json:"name" yaml:"name"`
+	Active    bool   `json:"active" yaml:"active"`
+	AppName   string `json:"appName" yaml:"appName"`
+	SecretKey string `json:"secretKey" yaml:"secretKey"`
+	Store     string `json:"store,omitempty" yaml:"store,omitempty"`
+}
+
+func (v ProfileView) csvHeader() []string {
+	return []string{"name", "active", "appName", "secretKey", "store"}
+}
+
+func (v ProfileView) csvRow() []string {
+	return []string{v.Name, strconv.FormatBool(v.Active), v.AppName, v.SecretKey, v.Store}
+}
+
+func addProfile(name string, flags *pflag.FlagSet) error {
+	if err := config.ValidateProfileName(name); err != nil {
+		return err
+	}
+	if profileStore != "" && !config.ValidStore(profileStore) {
+		return fmt.Errorf("invalid --store %q (use keyring, file or plain)", profileStore)
+	}
+
+	f, err := config.ReadOrEmpty()
+	if err != nil {
+		return err
+	}
+
+	p, _ := f.Profile(name)
+	if flags.Changed("app-name") {
+		p.AppName = profileAppName
+	}
+	if flags.Changed("api-url") {
+		p.APIURL = profileAPIURL
+	}
+	if flags.Changed("file-host") {
+		p.FileHost = profileFileHost
+	}
+	f.SetProfile(name, p)
+
+	if flags.Changed("secret") {
+		if err := config.StoreSecret(f, name, profileStore, profileSecretKey); err != nil {
+			return err
+		}
+	}
+
+	return config.Write(f)
+}
+
+func useProfile(name string) error {
+	f, err := config.Read()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := f.Profile(name); !ok {
+		return fmt.Errorf("profile %q: %w", name, config.ErrProfileNotFound)
+	}
+
+	f.Current = name
+	if name == config.DefaultProfile {
+		f.Current = ""
+	}
+
+	return config.Write(f)
+}
+
+func listProfiles() error {
+	f, err := config.ReadOrEmpty()
+	if err != nil {
+		return err
+	}
+
+	active := config.ActiveProfile(f)
+	var views []record
+	for _, name := range f.ProfileNames() {
+		p, _ := f.Profile(name)
+		view := ProfileView{Name: name, Active: name == active, AppName: p.AppName, Store: p.Store}
+		if p.SecretKey != "" {
+			view.SecretKey = maskSecretKey(p.SecretKey)
+		} else if p.Store != "" {
+			view.SecretKey = "(" + p.Store + ")"
+		}
+		views = append(views, view)
+	}
+
+	if !isTableOutput() {
+		return writeRecords(views...)
+	}
+
+	if len(views) == 0 {
+		fmt.Println("No profiles configured.")
+		return nil
+	}
+
+	for _, r := range views {
+		view := r.(ProfileView)
+		marker := " "
+		if view.Active {
+			marker = "*"
+		}
+		fmt.Printf("%s %-20s %-25s %s\n", marker, view.Name, view.AppName, view.SecretKey)
+	}
+	return nil
+}
+
+func removeProfile(name string) error {
+	f, err := config.Read()
+	if err != nil {
+		return err
+	}
+
+	p, ok := f.Profile(name)
+	if !ok {
+		return fmt.Errorf("profile %q: %w", name, config.ErrProfileNotFound)
+	}
+	if err := config.DeleteSecret(name, p); err != nil {
+		return err
+	}
+	f.RemoveProfile(name)
+
+	return config.Write(f)
+}
@@ -1,226 +1,378 @@
 package cmd
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
-	"mime/multipart"
-	"net/http"
+	"mime"
 	"os"
 	"path/filepath"
-	"time"
+	"strconv"
+	"strings"
+	"sync"
 
-	"ut/config"
+	"github.com/MhemedAbderrahmen/ut/config"
+	"github.com/MhemedAbderrahmen/ut/uploadthing"
 
 	"github.com/spf13/cobra"
 )
 
+const presignBatchSize = 20
+
+var (
+	uploadConcurrency int
+	partConcurrency   int
+	partRetries       int
+	uploadContentType string
+	uploadACL         string
+	uploadDisposition string
+)
+
 var uploadCmd = &cobra.Command{
 	Use:   "push <filepath> [filepath2] [filepath3]...",
 	Short: "Push one or more files to UploadThing",
-	Long:  `Push one or more files to UploadThing using your secret API key configured.`,
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Push one or more files to UploadThing using your secret API key configured.
+
+Files are presigned in batches and uploaded in parallel. A failed file does
+not stop the others; a summary is printed at the end and the command exits
+with a non-zero status if any file failed.
+
+Examples:
+  ut push document.pdf                     # Upload a single file
+  ut push *.png --concurrency 8            # Upload many files, 8 at a time
+  ut push ./dist -r                        # Upload a directory tree
+  ut push ./dist -r --exclude '*.map'      # Skip files matching a glob
+  ut push ./dist -r --relpath-as custom-id # Keep relative paths as custom IDs
+  ut push avatar.png --custom-id user-42   # Set the custom ID of a file
+  ut push ./img -r --custom-id 'img/{{.RelPath}}'  # Custom IDs from a template
+  ut push data.bin --content-type application/x-ndjson
+  ut push invoice.pdf --acl private        # Only downloadable with --private
+  ut push backup.zip --content-disposition attachment
+
+The Content-Type of each file is detected from its extension using the system
+MIME database, falling back to sniffing its contents. Extensions can be mapped
+to a type of your choice with 'ut config set-content-type'.
+
+The --custom-id value is a Go template with the fields .RelPath (the path
+relative to the pushed directory, or the file name), .Name, .Stem (the name
+without its extension), .Ext and .Path (the local path). Every file must end
+up with a different custom ID.
+
+When pushing a directory, patterns in its .utignore file are skipped as well.`,
+	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		for i, filePath := range args {
-			fmt.Printf("[%d/%d] Uploading %s...\n", i+1, len(args), filepath.Base(filePath))
-			err := uploadFile(filePath)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error uploading file %s: %v\n", filePath, err)
+		if uploadContentType != "" {
+			if _, _, err := mime.ParseMediaType(uploadContentType); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --content-type %q: %v\n", uploadContentType, err)
 				os.Exit(1)
 			}
-			fmt.Printf("[%d/%d] ✓ %s uploaded successfully!\n", i+1, len(args), filepath.Base(filePath))
 		}
-		if len(args) > 1 {
-			fmt.Printf("All %d files uploaded successfully!\n", len(args))
+		if err := validateACL(uploadACL); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if uploadDisposition != uploadthing.ContentDispositionInline && uploadDisposition != uploadthing.ContentDispositionAttachment {
+			fmt.Fprintf(os.Stderr, "Error: invalid --content-disposition %q (use %s or %s)\n", uploadDisposition, uploadthing.ContentDispositionInline, uploadthing.ContentDispositionAttachment)
+			os.Exit(1)
+		}
+
+		sources, err := collectUploadSources(args)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error collecting files: %v\n", err)
+			os.Exit(1)
+		}
+		if len(sources) == 0 {
+			infof("No files to upload.\n")
+			return
+		}
+
+		_, failed, err := runPush(cmd.Context(), sources, newUploadReporter(len(sources), os.Stdout))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error uploading files: %v\n", err)
+			exit(cmd.Context(), 1)
+		}
+		if failed > 0 {
+			exit(cmd.Context(), 1)
 		}
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(uploadCmd)
-}
 
-type UploadFilesRequest struct {
-	Files              []FileMetadata `json:"files"`
-	ACL                string         `json:"acl,omitempty"`
-	ContentDisposition string         `json:"contentDisposition,omitempty"`
+	uploadCmd.Flags().IntVarP(&uploadConcurrency, "concurrency", "c", 4, "Number of files uploaded in parallel")
+	uploadCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Upload directories recursively")
+	uploadCmd.Flags().StringArrayVar(&includePatterns, "include", nil, "Only upload files matching this glob (repeatable)")
+	uploadCmd.Flags().StringArrayVar(&excludePatterns, "exclude", nil, "Skip files and directories matching this glob (repeatable)")
+	uploadCmd.Flags().StringVar(&relPathAs, "relpath-as", relPathAsName, "Store the relative path of recursive uploads as the file name or custom ID (name, custom-id)")
+	uploadCmd.Flags().StringVar(&customIDFormat, "custom-id", "", "Custom ID for the uploaded file, or a template such as '{{.RelPath}}' for several files")
+	uploadCmd.Flags().StringVar(&uploadContentType, "content-type", "", "Content-Type for every uploaded file (default: detected from the extension and contents)")
+	uploadCmd.Flags().StringVar(&uploadACL, "acl", uploadthing.ACLPublicRead, "Access for the uploaded files (public-read, private)")
+	uploadCmd.Flags().StringVar(&uploadDisposition, "content-disposition", uploadthing.ContentDispositionInline, "Whether browsers display or download the files (inline, attachment)")
+	uploadCmd.Flags().IntVar(&partConcurrency, "part-concurrency", 4, "Number of parts uploaded in parallel for large files")
+	uploadCmd.Flags().IntVar(&partRetries, "part-retries", 3, "Number of retries for each failed part of a large file")
 }
 
-type FileMetadata struct {
-	Name     string `json:"name"`
-	Size     int64  `json:"size"`
-	Type     string `json:"type"`
-	CustomID string `json:"customId,omitempty"`
+type UploadResult struct {
+	Path     string `json:"path" yaml:"path"`
+	Name     string `json:"name" yaml:"name"`
+	Size     int64  `json:"size" yaml:"size"`
+	Key      string `json:"key,omitempty" yaml:"key,omitempty"`
+	CustomID string `json:"customId,omitempty" yaml:"customId,omitempty"`
+	FileURL  string `json:"url,omitempty" yaml:"url,omitempty"`
+	Error    string `json:"error,omitempty" yaml:"error,omitempty"`
 }
 
-type UploadFilesResponse struct {
-	Data []PresignedUpload `json:"data"`
+func (r UploadResult) csvHeader() []string {
+	return []string{"path", "name", "size", "key", "customId", "url", "error"}
 }
 
-type PresignedUpload struct {
-	URL                string            `json:"url"`
-	Fields             map[string]string `json:"fields"`
-	Key                string            `json:"key"`
-	FileName           string            `json:"fileName"`
-	FileType           string            `json:"fileType"`
-	FileUrl            string            `json:"fileUrl"`
-	ContentDisposition string            `json:"contentDisposition"`
+func (r UploadResult) csvRow() []string {
+	return []string{r.Path, r.Name, strconv.FormatInt(r.Size, 10), r.Key, r.CustomID, r.FileURL, r.Error}
 }
 
-func uploadFile(filePath string) error {
-	cfg, err := config.LoadConfig()
-	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
-	}
+// uploadJob is a local file waiting to be presigned and uploaded.
+type uploadJob struct {
+	path      string
+	file      *os.File
+	metadata  uploadthing.FileMetadata
+	presigned uploadthing.PresignedUpload
+}
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-	defer file.Close()
+// uploadReporter prints each finished upload as it completes and keeps the
+// totals for the final summary. It is safe for concurrent use.
+type uploadReporter struct {
+	mu       sync.Mutex
+	rw       *recordWriter
+	total    int
+	done     int
+	uploaded int
+	failed   int
+	skipped  int
+	results  []UploadResult
+}
 
-	fileInfo, err := file.Stat()
-	if err != nil {
-		return fmt.Errorf("failed to get file info: %w", err)
-	}
-
-	fileName := filepath.Base(file.Name())
-	fileSize := fileInfo.Size()
-
-	contentType := "application/octet-stream"
-	if ext := filepath.Ext(fileName); ext != "" {
-		switch ext {
-		case ".jpg", ".jpeg":
-			contentType = "image/jpeg"
-		case ".png":
-			contentType = "image/png"
-		case ".gif":
-			contentType = "image/gif"
-		case ".pdf":
-			contentType = "application/pdf"
-		case ".txt":
-			contentType = "text/plain"
-		case ".json":
-			contentType = "application/json"
-		case ".xml":
-			contentType = "application/xml"
-		case ".csv":
-			contentType = "text/csv"
-		}
+func newUploadReporter(total int, w io.Writer) *uploadReporter {
+	return &uploadReporter{
+		rw:    newRecordWriter(w, outputFormat),
+		total: total,
 	}
+}
 
-	uploadReq := UploadFilesRequest{
-		Files: []FileMetadata{
-			{
-				Name: fileName,
-				Size: fileSize,
-				Type: contentType,
-			},
-		},
-		ACL:                "public-read",
-		ContentDisposition: "inline",
+func (r *uploadReporter) report(result UploadResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.done++
+	r.results = append(r.results, result)
+	if result.Error != "" {
+		r.failed++
+		fmt.Fprintf(os.Stderr, "[%d/%d] ✗ %s: %s\n", r.done, r.total, result.Path, result.Error)
+	} else {
+		r.uploaded++
+		infof("[%d/%d] ✓ %s uploaded successfully!\n", r.done, r.total, result.Path)
 	}
 
-	reqBody, err := json.Marshal(uploadReq)
-	if err != nil {
-		return fmt.Errorf("failed to marshal upload request: %w", err)
+	if isTableOutput() {
+		if result.Error == "" {
+			fmt.Printf("📄 %s\n", result.Path)
+			fmt.Printf("   File Key: %s\n", result.Key)
+			if result.CustomID != "" {
+				fmt.Printf("   Custom ID: %s\n", result.CustomID)
+			}
+			fmt.Printf("   File URL: %s\n", result.FileURL)
+		}
+		return
 	}
-
-	fmt.Printf("Requesting presigned URL...\n")
-
-	apiURL := "https://api.uploadthing.com/v6/uploadFiles"
-	req, err := http.NewRequest(http.MethodPost, apiURL, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return fmt.Errorf("failed to create upload request: %w", err)
+	if err := r.rw.Write(result); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
 	}
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Uploadthing-Api-Key", cfg.SecretKey)
+// skip counts a file that was not uploaded because the command was stopped.
+func (r *uploadReporter) skip() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	client := &http.Client{Timeout: 60 * time.Second}
+	r.skipped++
+}
 
-	resp, err := client.Do(req)
+// runPush uploads sources and reports each result to reporter. It returns all
+// results and the number of failed uploads. When ctx is canceled, uploads in
+// flight fail, files not yet started are skipped and the cause is returned
+// after the summary.
+func runPush(ctx context.Context, sources []uploadSource, reporter *uploadReporter) ([]UploadResult, int, error) {
+	cfg, err := config.LoadConfig()
 	if err != nil {
-		return fmt.Errorf("upload request failed: %w", err)
+		return nil, 0, fmt.Errorf("failed to load config: %w", err)
 	}
-	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read upload response: %w", err)
+	workers := uploadConcurrency
+	if workers < 1 {
+		workers = 1
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to get presigned URL: status %d, response: %s", resp.StatusCode, string(respBody))
+	defer reporter.rw.Close()
+
+	client := newClient(cfg)
+
+	jobs := make(chan *uploadJob)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if ctx.Err() != nil {
+					job.file.Close()
+					reporter.skip()
+					continue
+				}
+				reporter.report(performUpload(ctx, client, job))
+			}
+		}()
 	}
 
-	var uploadResp UploadFilesResponse
-	if err := json.Unmarshal(respBody, &uploadResp); err != nil {
-		return fmt.Errorf("failed to unmarshal upload response: %w", err)
+	var batch []*uploadJob
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := presignUploads(ctx, client, batch); err != nil {
+			for _, job := range batch {
+				job.file.Close()
+				if ctx.Err() != nil {
+					reporter.skip()
+				} else {
+					reporter.report(failedUpload(job.path, job.metadata, err))
+				}
+			}
+		} else {
+			for _, job := range batch {
+				jobs <- job
+			}
+		}
+		batch = nil
 	}
 
-	if len(uploadResp.Data) == 0 {
-		return fmt.Errorf("no presigned upload data received from UploadThing")
+	for _, src := range sources {
+		if ctx.Err() != nil {
+			reporter.skip()
+			continue
+		}
+		job, err := prepareUpload(src, cfg.ContentTypes)
+		if err != nil {
+			reporter.report(failedUpload(src.Path, uploadthing.FileMetadata{Name: src.Name, CustomID: src.CustomID}, err))
+			continue
+		}
+		batch = append(batch, job)
+		if len(batch) == presignBatchSize {
+			flush()
+		}
 	}
+	flush()
 
-	presignedUpload := uploadResp.Data[0]
-	fmt.Printf("Got presigned URL: %s\n", presignedUpload.URL)
+	close(jobs)
+	wg.Wait()
 
-	file.Seek(0, 0)
-
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	for key, value := range presignedUpload.Fields {
-		err := writer.WriteField(key, value)
-		if err != nil {
-			return fmt.Errorf("failed to write field %s: %w", key, err)
-		}
+	if reporter.skipped > 0 {
+		infof("\n%d uploaded, %d failed, %d not started.\n", reporter.uploaded, reporter.failed, reporter.skipped)
+	} else if reporter.total > 1 {
+		infof("\n%d uploaded, %d failed.\n", reporter.uploaded, reporter.failed)
 	}
+	if ctx.Err() != nil {
+		return reporter.results, reporter.failed, context.Cause(ctx)
+	}
+	return reporter.results, reporter.failed, nil
+}
 
-	part, err := writer.CreateFormFile("file", fileName)
+func prepareUpload(src uploadSource, contentTypes map[string]string) (*uploadJob, error) {
+	file, err := os.Open(src.Path)
 	if err != nil {
-		return fmt.Errorf("failed to create form file: %w", err)
+		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 
-	_, err = io.Copy(part, file)
+	fileInfo, err := file.Stat()
 	if err != nil {
-		return fmt.Errorf("failed to copy file content: %w", err)
+		file.Close()
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+	if fileInfo.IsDir() {
+		file.Close()
+		return nil, fmt.Errorf("%s is a directory (use -r to upload directories)", src.Path)
 	}
 
-	err = writer.Close()
-	if err != nil {
-		return fmt.Errorf("failed to close multipart writer: %w", err)
+	return &uploadJob{
+		path: src.Path,
+		file: file,
+		metadata: uploadthing.FileMetadata{
+			Name:     src.Name,
+			Size:     fileInfo.Size(),
+			Type:     contentTypeFor(src.Name, file, contentTypes),
+			CustomID: src.CustomID,
+		},
+	}, nil
+}
+
+// contentTypeFor picks the Content-Type of an upload: the --content-type
+// flag, then the extension mapping in the config file, then detection from
+// the extension and contents.
+func contentTypeFor(fileName string, file io.ReaderAt, contentTypes map[string]string) string {
+	if uploadContentType != "" {
+		return uploadContentType
+	}
+	if contentType, ok := contentTypes[strings.ToLower(filepath.Ext(fileName))]; ok {
+		return contentType
+	}
+	return uploadthing.DetectContentType(fileName, file)
+}
+
+// presignUploads requests presigned uploads for every job in one uploadFiles
+// call. UploadThing returns them in the same order as the request.
+func presignUploads(ctx context.Context, client *uploadthing.Client, batch []*uploadJob) error {
+	uploadReq := uploadthing.UploadFilesRequest{
+		ACL:                uploadACL,
+		ContentDisposition: uploadDisposition,
+	}
+	for _, job := range batch {
+		uploadReq.Files = append(uploadReq.Files, job.metadata)
 	}
 
-	uploadFileReq, err := http.NewRequest(http.MethodPost, presignedUpload.URL, body)
+	uploads, err := client.UploadFiles(ctx, uploadReq)
 	if err != nil {
-		return fmt.Errorf("failed to create file upload request: %w", err)
+		return fmt.Errorf("failed to get presigned URL: %w", err)
 	}
 
-	uploadFileReq.Header.Set("Content-Type", writer.FormDataContentType())
+	for i, job := range batch {
+		job.presigned = uploads[i]
+	}
+	return nil
+}
 
-	fmt.Printf("Uploading file to storage...\n")
+func performUpload(ctx context.Context, client *uploadthing.Client, job *uploadJob) UploadResult {
+	defer job.file.Close()
 
-	uploadFileResp, err := client.Do(uploadFileReq)
+	err := client.UploadPresigned(ctx, job.presigned, job.metadata.Name, job.file, job.metadata.Size)
 	if err != nil {
-		return fmt.Errorf("file upload request failed: %w", err)
+		return failedUpload(job.path, job.metadata, err)
 	}
-	defer uploadFileResp.Body.Close()
 
-	uploadFileRespBody, err := io.ReadAll(uploadFileResp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read file upload response: %w", err)
+	return UploadResult{
+		Path:     job.path,
+		Name:     job.metadata.Name,
+		Size:     job.metadata.Size,
+		Key:      job.presigned.Key,
+		CustomID: job.metadata.CustomID,
+		FileURL:  job.presigned.FileUrl,
 	}
+}
 
-	if uploadFileResp.StatusCode < 200 || uploadFileResp.StatusCode >= 300 {
-		return fmt.Errorf("file upload failed: status %d, response: %s", uploadFileResp.StatusCode, string(uploadFileRespBody))
+func failedUpload(path string, metadata uploadthing.FileMetadata, err error) UploadResult {
+	return UploadResult{
+		Path:     path,
+		Name:     metadata.Name,
+		Size:     metadata.Size,
+		CustomID: metadata.CustomID,
+		Error:    err.Error(),
 	}
-
-	fmt.Printf("Upload successful!\n")
-	fmt.Printf("File key: %s\n", presignedUpload.Key)
-	fmt.Printf("File URL: %s\n", presignedUpload.FileUrl)
-
-	return nil
 }
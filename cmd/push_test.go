@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestContentTypeFor(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 16)
+	mapping := map[string]string{".webp": "image/x-custom-webp", ".dat": "application/x-dat"}
+
+	tests := []struct {
+		name    string
+		flag    string
+		mapping map[string]string
+		file    string
+		content string
+		want    string
+	}{
+		{"flag wins over everything", "text/plain", mapping, "photo.webp", png, "text/plain"},
+		{"config mapping wins over detection", "", mapping, "photo.webp", png, "image/x-custom-webp"},
+		{"config mapping ignores case", "", mapping, "PHOTO.WEBP", "", "image/x-custom-webp"},
+		{"config mapping for an unknown extension", "", mapping, "blob.dat", png, "application/x-dat"},
+		{"detected from the extension", "", mapping, "logo.svg", "", "image/svg+xml"},
+		{"detected from the extension without a mapping", "", nil, "photo.webp", "", "image/webp"},
+		{"sniffed when the extension is unknown", "", mapping, "image.unknownext", png, "image/png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uploadContentType = tt.flag
+			defer func() { uploadContentType = "" }()
+
+			if got := contentTypeFor(tt.file, strings.NewReader(tt.content), tt.mapping); got != tt.want {
+				t.Errorf("contentTypeFor(%q) = %q, want %q", tt.file, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/MhemedAbderrahmen/ut/config"
+	"github.com/MhemedAbderrahmen/ut/uploadthing"
+
+	"github.com/spf13/cobra"
+)
+
+// renameBatchSize is the number of renames sent in one renameFiles call.
+const renameBatchSize = 100
+
+var (
+	renameByCustomID bool
+	renameCSV        string
+)
+
+var renameCmd = &cobra.Command{
+	Use:   "rename <fileKey> <newName>",
+	Short: "Rename files on UploadThing",
+	Long: `Change the name of a file on UploadThing, identified by its file key or, with
+--custom-id, by its custom ID.
+
+With --csv, renames are read from a CSV file ("-" for stdin) with one
+"key,newName" pair per row. A header row starting with "key", "fileKey" or
+"customId" is skipped. Renames are sent in batches and a failed batch does not
+stop the others.
+
+Custom IDs cannot be changed: UploadThing's rename API only updates the
+name, and it has no call for setting the custom ID of an existing file.
+Upload the file again with 'ut push --custom-id' and delete the old copy
+instead.
+
+Examples:
+  ut rename abc123-IMG_0042.jpg beach.jpg        # Rename by file key
+  ut rename --custom-id avatar-42 avatar.png     # Rename by custom ID
+  ut rename --csv renames.csv                    # Rename many files at once
+  ut rename --csv - < renames.csv                # Read renames from stdin`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if renameCSV != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(2)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		failed, err := runRename(cmd.Context(), args)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error renaming files: %v\n", err)
+			exit(cmd.Context(), 1)
+		}
+		if failed > 0 {
+			exit(cmd.Context(), 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(renameCmd)
+
+	renameCmd.Flags().BoolVar(&renameByCustomID, "custom-id", false, "Identify files by custom ID instead of file key")
+	renameCmd.Flags().StringVar(&renameCSV, "csv", "", `Read "key,newName" rows from a CSV file ("-" for stdin)`)
+}
+
+type RenameResult struct {
+	Key     string `json:"key" yaml:"key"`
+	NewName string `json:"newName" yaml:"newName"`
+	Renamed bool   `json:"renamed" yaml:"renamed"`
+	Error   string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func (r RenameResult) csvHeader() []string {
+	return []string{"key", "newName", "renamed", "error"}
+}
+
+func (r RenameResult) csvRow() []string {
+	return []string{r.Key, r.NewName, strconv.FormatBool(r.Renamed), r.Error}
+}
+
+func runRename(ctx context.Context, args []string) (int, error) {
+	var updates []uploadthing.RenameUpdate
+	if renameCSV != "" {
+		var err error
+		updates, err = readRenameCSV(renameCSV)
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		update, err := newRenameUpdate(args[0], args[1])
+		if err != nil {
+			return 0, err
+		}
+		updates = append(updates, update)
+	}
+	if len(updates) == 0 {
+		infof("No files to rename.\n")
+		return 0, nil
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	client := newClient(cfg)
+
+	rw := newRecordWriter(os.Stdout, outputFormat)
+	defer rw.Close()
+
+	renamed, failed := 0, 0
+	for start := 0; start < len(updates); start += renameBatchSize {
+		if ctx.Err() != nil {
+			infof("\n%d renamed, %d failed, %d not started.\n", renamed, failed, len(updates)-start)
+			return failed, context.Cause(ctx)
+		}
+
+		batch := updates[start:min(start+renameBatchSize, len(updates))]
+		err := renameFiles(ctx, client, batch)
+
+		for _, update := range batch {
+			result := RenameResult{Key: renameKey(update), NewName: update.NewName, Renamed: err == nil}
+			if err != nil {
+				result.Error = err.Error()
+				failed++
+			} else {
+				renamed++
+			}
+
+			if !isTableOutput() {
+				if err := rw.Write(result); err != nil {
+					return failed, fmt.Errorf("failed to write output: %w", err)
+				}
+			} else if result.Renamed {
+				fmt.Printf("✓ %s → %s\n", result.Key, result.NewName)
+			} else {
+				fmt.Fprintf(os.Stderr, "✗ %s: %s\n", result.Key, result.Error)
+			}
+		}
+	}
+
+	if len(updates) > 1 {
+		infof("\n%d renamed, %d failed.\n", renamed, failed)
+	}
+	return failed, nil
+}
+
+func renameFiles(ctx context.Context, client *uploadthing.Client, updates []uploadthing.RenameUpdate) error {
+	resp, err := client.RenameFiles(ctx, uploadthing.RenameFilesRequest{Updates: updates})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return errors.New("UploadThing did not accept the rename")
+	}
+	return nil
+}
+
+func newRenameUpdate(key, newName string) (uploadthing.RenameUpdate, error) {
+	key = strings.TrimSpace(key)
+	newName = strings.TrimSpace(newName)
+	if key == "" {
+		return uploadthing.RenameUpdate{}, errors.New("file key cannot be empty")
+	}
+	if newName == "" {
+		return uploadthing.RenameUpdate{}, fmt.Errorf("new name for %s cannot be empty", key)
+	}
+
+	if renameByCustomID {
+		return uploadthing.RenameUpdate{CustomID: key, NewName: newName}, nil
+	}
+	return uploadthing.RenameUpdate{FileKey: key, NewName: newName}, nil
+}
+
+func renameKey(update uploadthing.RenameUpdate) string {
+	if update.CustomID != "" {
+		return update.CustomID
+	}
+	return update.FileKey
+}
+
+// readRenameCSV reads "key,newName" rows from path, or from stdin for "-".
+func readRenameCSV(path string) ([]uploadthing.RenameUpdate, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open CSV file: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+
+	var updates []uploadthing.RenameUpdate
+	for first := true; ; first = false {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		if first && len(row) > 0 {
+			switch strings.ToLower(strings.TrimSpace(row[0])) {
+			case "key", "filekey", "customid", "custom_id":
+				continue
+			}
+		}
+		if len(row) < 2 {
+			return nil, fmt.Errorf("CSV line %d: expected key,newName", line)
+		}
+
+		update, err := newRenameUpdate(row[0], row[1])
+		if err != nil {
+			return nil, fmt.Errorf("CSV line %d: %w", line, err)
+		}
+		updates = append(updates, update)
+	}
+	return updates, nil
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MhemedAbderrahmen/ut/uploadthing"
+)
+
+func TestReadRenameCSV(t *testing.T) {
+	tests := []struct {
+		name     string
+		csv      string
+		byCustom bool
+		want     []uploadthing.RenameUpdate
+		wantErr  string
+	}{
+		{
+			name: "without header",
+			csv:  "k1,a.txt\nk2, b.txt \n",
+			want: []uploadthing.RenameUpdate{{FileKey: "k1", NewName: "a.txt"}, {FileKey: "k2", NewName: "b.txt"}},
+		},
+		{
+			name: "header skipped",
+			csv:  "key,newName\nk1,a.txt\n",
+			want: []uploadthing.RenameUpdate{{FileKey: "k1", NewName: "a.txt"}},
+		},
+		{
+			name: "header after comments skipped",
+			csv:  "# exported renames\nfileKey,newName\nk1,a.txt\n",
+			want: []uploadthing.RenameUpdate{{FileKey: "k1", NewName: "a.txt"}},
+		},
+		{
+			name: "header only on the first record",
+			csv:  "k1,a.txt\nkey,b.txt\n",
+			want: []uploadthing.RenameUpdate{{FileKey: "k1", NewName: "a.txt"}, {FileKey: "key", NewName: "b.txt"}},
+		},
+		{
+			name:     "custom IDs",
+			csv:      "customId,newName\navatar-42,avatar.png\n",
+			byCustom: true,
+			want:     []uploadthing.RenameUpdate{{CustomID: "avatar-42", NewName: "avatar.png"}},
+		},
+		{
+			name: "quoted name with comma",
+			csv:  "k1,\"a, b.txt\"\n",
+			want: []uploadthing.RenameUpdate{{FileKey: "k1", NewName: "a, b.txt"}},
+		},
+		{
+			name:    "missing name",
+			csv:     "k1,a.txt\nk2\n",
+			wantErr: "CSV line 2: expected key,newName",
+		},
+		{
+			name:    "empty name after comments and a multi-line field",
+			csv:     "# comment\nk1,\"multi\nline.txt\"\n\n# another\nk2,  \n",
+			wantErr: "CSV line 6: new name for k2 cannot be empty",
+		},
+		{
+			name:    "empty key",
+			csv:     "key,newName\n ,a.txt\n",
+			wantErr: "CSV line 2: file key cannot be empty",
+		},
+		{
+			name:    "unterminated quote",
+			csv:     "k1,\"a.txt\n",
+			wantErr: "failed to read CSV",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "renames.csv")
+			if err := os.WriteFile(path, []byte(tt.csv), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			renameByCustomID = tt.byCustom
+			defer func() { renameByCustomID = false }()
+
+			got, err := readRenameCSV(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readRenameCSV: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("updates = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MhemedAbderrahmen/ut/uploadthing"
+)
+
+const (
+	partSuffix = ".part"
+	metaSuffix = ".part.meta"
+)
+
+// partMeta records the validators of the response a .part file was started
+// from, so a later resume only appends bytes from the same version of the file.
+type partMeta struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Size         int64  `json:"size,omitempty"`
+}
+
+func (m partMeta) validator() string {
+	if m.ETag != "" && !strings.HasPrefix(m.ETag, "W/") {
+		return m.ETag
+	}
+	return m.LastModified
+}
+
+func readPartMeta(path string) (partMeta, bool) {
+	var meta partMeta
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return meta, false
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return meta, false
+	}
+	return meta, meta.validator() != ""
+}
+
+func writePartMeta(path string, meta partMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// resumableDownload downloads fileURL into outputFilePath via a .part file.
+// If a .part file from an earlier attempt exists, it continues from its current
+// size using a Range request guarded by If-Range. The .part file is kept on
+// failure and renamed into place once the download is complete.
+func resumableDownload(ctx context.Context, client *uploadthing.Client, fileURL, outputFilePath string, progress bool) (int64, error) {
+	partPath := outputFilePath + partSuffix
+	metaPath := outputFilePath + metaSuffix
+
+	partFile, err := os.OpenFile(partPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("unable to open partial file %s: %w", partPath, err)
+	}
+	defer partFile.Close()
+
+	stat, err := partFile.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("unable to stat partial file: %w", err)
+	}
+
+	offset := stat.Size()
+	meta, haveMeta := readPartMeta(metaPath)
+	if offset > 0 && !haveMeta {
+		infof("No validator stored for %s, starting over\n", partPath)
+		offset = 0
+	}
+
+	resp, err := client.DownloadRange(ctx, fileURL, offset, meta.validator())
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			return 0, fmt.Errorf("server returned unexpected range %q", resp.Header.Get("Content-Range"))
+		}
+		infof("Resuming at %s\n", formatFileSize(offset))
+		meta.Size = total
+	case http.StatusOK:
+		if offset > 0 {
+			infof("Remote file changed or range not supported, starting over\n")
+		}
+		offset = 0
+		meta = partMeta{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			Size:         resp.ContentLength,
+		}
+		if err := writePartMeta(metaPath, meta); err != nil {
+			return 0, fmt.Errorf("unable to write %s: %w", metaPath, err)
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		_, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || total != offset {
+			return 0, fmt.Errorf("server rejected resume at byte %d; remove %s to start over", offset, partPath)
+		}
+		return finishPartFile(partFile, partPath, metaPath, outputFilePath, offset)
+	default:
+		return 0, fmt.Errorf("HTTP error: %s", resp.Status)
+	}
+
+	if err := partFile.Truncate(offset); err != nil {
+		return 0, fmt.Errorf("unable to truncate partial file: %w", err)
+	}
+	if _, err := partFile.Seek(offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("unable to seek partial file: %w", err)
+	}
+
+	var src io.Reader = resp.Body
+	var progressWriter *ProgressWriter
+	if progress {
+		progressWriter = &ProgressWriter{
+			Total:      meta.Size,
+			Downloaded: offset,
+			StartTime:  time.Now(),
+		}
+		src = io.TeeReader(resp.Body, progressWriter)
+	}
+
+	written, err := io.Copy(partFile, src)
+	if progressWriter != nil {
+		infof("\n")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to write file (partial download kept at %s, rerun with --resume): %w", partPath, err)
+	}
+
+	size := offset + written
+	if meta.Size > 0 && size != meta.Size {
+		return 0, fmt.Errorf("download incomplete: got %s of %s (partial download kept at %s, rerun with --resume)",
+			formatFileSize(size), formatFileSize(meta.Size), partPath)
+	}
+
+	return finishPartFile(partFile, partPath, metaPath, outputFilePath, size)
+}
+
+func finishPartFile(partFile *os.File, partPath, metaPath, outputFilePath string, size int64) (int64, error) {
+	if err := partFile.Sync(); err != nil {
+		return 0, fmt.Errorf("unable to flush partial file: %w", err)
+	}
+	if err := partFile.Close(); err != nil {
+		return 0, fmt.Errorf("unable to close partial file: %w", err)
+	}
+	if err := os.Rename(partPath, outputFilePath); err != nil {
+		return 0, fmt.Errorf("unable to move %s into place: %w", partPath, err)
+	}
+	os.Remove(metaPath)
+	return size, nil
+}
+
+// parseContentRange parses "bytes start-end/total" and "bytes */total" values.
+// total is -1 when the server does not know the complete length.
+func parseContentRange(value string) (start, total int64, ok bool) {
+	spec, found := strings.CutPrefix(value, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	rangePart, totalPart, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, false
+	}
+
+	total = -1
+	if totalPart != "*" {
+		n, err := strconv.ParseInt(totalPart, 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		total = n
+	}
+
+	if rangePart == "*" {
+		return 0, total, true
+	}
+	startPart, _, found := strings.Cut(rangePart, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(startPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, total, true
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MhemedAbderrahmen/ut/config"
+)
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		value     string
+		wantStart int64
+		wantTotal int64
+		wantOK    bool
+	}{
+		{"bytes 0-99/100", 0, 100, true},
+		{"bytes 50-99/100", 50, 100, true},
+		{"bytes 50-99/*", 50, -1, true},
+		{"bytes */100", 0, 100, true},
+		{"bytes */*", 0, -1, true},
+		{"", 0, 0, false},
+		{"items 0-9/10", 0, 0, false},
+		{"bytes 0-99", 0, 0, false},
+		{"bytes x-99/100", 0, 0, false},
+		{"bytes 0-99/abc", 0, 0, false},
+		{"bytes 99/100", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		start, total, ok := parseContentRange(tt.value)
+		if start != tt.wantStart || total != tt.wantTotal || ok != tt.wantOK {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v; want %d, %d, %v",
+				tt.value, start, total, ok, tt.wantStart, tt.wantTotal, tt.wantOK)
+		}
+	}
+}
+
+// rangeServer serves content with the given ETag, honoring Range and If-Range
+// the way a file host does, and records the Range header of each request.
+func rangeServer(content, etag string, ranges *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*ranges = append(*ranges, r.Header.Get("Range"))
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "file.bin", time.Time{}, strings.NewReader(content))
+	}))
+}
+
+func TestResumableDownload(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+
+	tests := []struct {
+		name      string
+		part      string // existing .part content, if any
+		partETag  string // ETag in the .part.meta file, if any
+		wantRange string
+	}{
+		{"fresh download", "", "", ""},
+		{"resume", content[:400], `"v1"`, "bytes=400-"},
+		{"validator mismatch restarts", "stale bytes from an old version", `"v0"`, "bytes=31-"},
+		{"part without validator restarts", content[:400], "", ""},
+		{"complete part answered with 416", content, `"v1"`, "bytes=1000-"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ranges []string
+			srv := rangeServer(content, `"v1"`, &ranges)
+			defer srv.Close()
+
+			output := filepath.Join(t.TempDir(), "file.bin")
+			if tt.part != "" {
+				if err := os.WriteFile(output+partSuffix, []byte(tt.part), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.partETag != "" {
+				if err := writePartMeta(output+metaSuffix, partMeta{ETag: tt.partETag}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			size, err := resumableDownload(context.Background(), newClient(&config.Config{}), srv.URL, output, false)
+			if err != nil {
+				t.Fatalf("resumableDownload: %v", err)
+			}
+
+			if len(ranges) != 1 || ranges[0] != tt.wantRange {
+				t.Errorf("Range headers = %q, want [%q]", ranges, tt.wantRange)
+			}
+			got, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, []byte(content)) || size != int64(len(content)) {
+				t.Errorf("downloaded %d bytes (reported %d), want the %d byte file", len(got), size, len(content))
+			}
+			for _, leftover := range []string{output + partSuffix, output + metaSuffix} {
+				if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+					t.Errorf("%s was not removed", filepath.Base(leftover))
+				}
+			}
+		})
+	}
+}
+
+func TestResumableDownloadRejectsOversizedPart(t *testing.T) {
+	var ranges []string
+	srv := rangeServer("short", `"v1"`, &ranges)
+	defer srv.Close()
+
+	output := filepath.Join(t.TempDir(), "file.bin")
+	os.WriteFile(output+partSuffix, []byte("longer than the remote file"), 0644)
+	writePartMeta(output+metaSuffix, partMeta{ETag: `"v1"`})
+
+	_, err := resumableDownload(context.Background(), newClient(&config.Config{}), srv.URL, output, false)
+	if err == nil || !strings.Contains(err.Error(), "rejected resume") {
+		t.Fatalf("error = %v, want the resume to be rejected", err)
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Error("output file created from a mismatched .part file")
+	}
+	if _, err := os.Stat(output + partSuffix); err != nil {
+		t.Errorf(".part file not kept: %v", err)
+	}
+}
+
+func TestResumableDownloadKeepsPartOnShortBody(t *testing.T) {
+	content := strings.Repeat("x", 1000)
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("ETag", `"v1"`)
+		if calls == 1 {
+			// Promise the whole file but drop the connection halfway.
+			w.Header().Set("Content-Length", "1000")
+			w.Write([]byte(content[:600]))
+			w.(http.Flusher).Flush()
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		http.ServeContent(w, r, "file.bin", time.Time{}, strings.NewReader(content))
+	}))
+	defer srv.Close()
+
+	output := filepath.Join(t.TempDir(), "file.bin")
+	client := newClient(&config.Config{})
+	client.Retries = 0
+
+	if _, err := resumableDownload(context.Background(), client, srv.URL, output, false); err == nil {
+		t.Fatal("first attempt succeeded on a truncated body")
+	}
+	if part, _ := os.ReadFile(output + partSuffix); len(part) != 600 {
+		t.Fatalf(".part file has %d bytes, want the 600 received", len(part))
+	}
+
+	size, err := resumableDownload(context.Background(), client, srv.URL, output, false)
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	got, _ := os.ReadFile(output)
+	if size != 1000 || string(got) != content {
+		t.Errorf("resumed download has %d bytes, want %d", len(got), len(content))
+	}
+}
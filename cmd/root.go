@@ -1,11 +1,45 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/MhemedAbderrahmen/ut/config"
+	"github.com/MhemedAbderrahmen/ut/uploadthing"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	configFlag  string
+	profileFlag string
+	secretFlag  string
+	apiURLFlag  string
+	hostFlag    string
+
+	retries      int
+	retryMaxWait time.Duration
+	timeout      time.Duration
+
+	cancelTimeout context.CancelFunc = func() {}
+)
+
+// Exit codes for commands that were stopped before they finished, following
+// the shell convention for SIGINT and the timeout(1) utility.
+const (
+	exitInterrupted = 130
+	exitTimeout     = 124
 )
 
+var errInterrupted = errors.New("interrupted")
+
 var rootCmd = &cobra.Command{
 	Use:   "ut",
 	Short: "UploadThing CLI - Upload and manage files from your terminal",
@@ -16,15 +50,131 @@ from your terminal with features like progress tracking, custom output paths,
 and support for both public and private files.
 
 Visit https://uploadthing.com to get your API key and start using the CLI.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if configFlag != "" {
+			config.SetPathOverride(configFlag)
+		}
+		if cmd.Flags().Changed("profile") {
+			if err := config.ValidateProfileName(profileFlag); err != nil {
+				return err
+			}
+			config.SetProfileOverride(profileFlag)
+		}
+		if secretFlag != "" {
+			config.SetSecretOverride(secretFlag)
+		}
+		if apiURLFlag != "" || hostFlag != "" {
+			config.SetEndpointOverride(apiURLFlag, hostFlag)
+		}
+		if retries < 0 {
+			return fmt.Errorf("--retries cannot be negative")
+		}
+		if timeout < 0 {
+			return fmt.Errorf("--timeout cannot be negative")
+		}
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			cancelTimeout = cancel
+			cmd.SetContext(ctx)
+		}
+		return validateOutputFormat()
+	},
 }
 
 func Execute() {
-	err := rootCmd.Execute()
+	ctx := interruptContext()
+	err := rootCmd.ExecuteContext(ctx)
+	cancelTimeout()
 	if err != nil {
-		os.Exit(1)
+		exit(ctx, 1)
+	}
+}
+
+// prompt tracks whether the command is waiting for the user to answer a
+// prompt on stdin, and the terminal state to restore if it is interrupted
+// there. Hidden prompts turn off echo until they return.
+var prompt struct {
+	sync.Mutex
+	active bool
+	state  *term.State
+}
+
+// beginPrompt marks the start of a prompt and returns the function that
+// marks its end.
+func beginPrompt() func() {
+	prompt.Lock()
+	defer prompt.Unlock()
+
+	prompt.active = true
+	prompt.state = nil
+	if state, err := term.GetState(int(os.Stdin.Fd())); err == nil {
+		prompt.state = state
+	}
+
+	return func() {
+		prompt.Lock()
+		defer prompt.Unlock()
+		prompt.active = false
+		prompt.state = nil
+	}
+}
+
+// interruptContext returns a context that is canceled with errInterrupted on
+// SIGINT or SIGTERM, so that transfers stop cleanly and report what finished.
+// A second signal exits immediately. At a prompt nothing is in flight, so the
+// first signal restores the terminal and exits.
+func interruptContext() context.Context {
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for interrupted := false; ; interrupted = true {
+			<-signals
+
+			prompt.Lock()
+			if prompt.active {
+				if prompt.state != nil {
+					term.Restore(int(os.Stdin.Fd()), prompt.state)
+				}
+				fmt.Fprintln(os.Stderr)
+				os.Exit(exitInterrupted)
+			}
+			prompt.Unlock()
+
+			if interrupted {
+				os.Exit(exitInterrupted)
+			}
+			fmt.Fprintln(os.Stderr, "\nInterrupted, stopping... (press Ctrl-C again to quit immediately)")
+			cancel(errInterrupted)
+		}
+	}()
+
+	return ctx
+}
+
+// exit ends the process with code, or with exitInterrupted or exitTimeout
+// when the command was stopped by a signal or by --timeout.
+func exit(ctx context.Context, code int) {
+	switch {
+	case errors.Is(context.Cause(ctx), errInterrupted):
+		os.Exit(exitInterrupted)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		fmt.Fprintf(os.Stderr, "Timed out after %s\n", timeout)
+		os.Exit(exitTimeout)
 	}
+	os.Exit(code)
 }
 
 func init() {
 	rootCmd.Flags().BoolP("version", "v", false, "Show version information")
+	rootCmd.PersistentFlags().StringVar(&configFlag, "config", "", "Config file to use (default: $UT_CONFIG, the saved config path or ~/.ut-cli/config.yml)")
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "Configuration profile to use (default: $UT_PROFILE or the selected profile)")
+	rootCmd.PersistentFlags().StringVar(&secretFlag, "secret", "", "UploadThing secret key (overrides $UPLOADTHING_SECRET, $UPLOADTHING_TOKEN and the config file)")
+	rootCmd.PersistentFlags().StringVar(&apiURLFlag, "api-url", "", "UploadThing API base URL (default: $UT_API_URL, the profile setting or https://api.uploadthing.com)")
+	rootCmd.PersistentFlags().StringVar(&hostFlag, "file-host", "", "Host files are downloaded from, e.g. https://{appId}.ufs.sh (default: $UT_FILE_HOST, the profile setting or https://utfs.io)")
+	rootCmd.PersistentFlags().IntVar(&retries, "retries", uploadthing.DefaultRetries, "Number of retries for API calls and transfers that fail with a transient error")
+	rootCmd.PersistentFlags().DurationVar(&retryMaxWait, "retry-max-wait", uploadthing.DefaultRetryMaxWait, "Longest wait before a retry; a longer Retry-After from the server is not waited for")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "Stop the command after this long, e.g. 10m (default: no limit)")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "format", formatTable, "Output format: table, json, ndjson, yaml or csv")
 }
@@ -0,0 +1,505 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/MhemedAbderrahmen/ut/config"
+	"github.com/MhemedAbderrahmen/ut/uploadthing"
+
+	"github.com/spf13/cobra"
+)
+
+const syncManifestName = ".utsync.json"
+
+const (
+	syncActionUpload  = "upload"
+	syncActionReplace = "replace"
+	syncActionDelete  = "delete"
+)
+
+var (
+	syncDelete bool
+	syncDryRun bool
+	syncYes    bool
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync <dir>",
+	Short: "Mirror a local directory to UploadThing",
+	Long: `Compare a local directory with the files in your UploadThing app and upload
+only what is new or changed.
+
+Remote files are matched by name against the path relative to <dir>. Files
+with the same size are compared by the SHA-256 hash recorded in
+<dir>/` + syncManifestName + ` on the previous sync. A same-size file with no
+record for its remote copy cannot be compared and is uploaded again, so keep
+the manifest between runs (for example in a CI cache) to avoid re-uploads.
+
+The directory's .utignore file and the --include/--exclude globs are honored.
+With --delete, remote files whose names they skip are kept, so excluding a
+path never deletes it. If several remote files share a synced name, the one
+recorded in the manifest is kept and the others are removed with --delete;
+without a manifest entry the sync stops rather than guess.
+
+Examples:
+  ut sync ./dist                  # Upload new and changed files
+  ut sync ./dist --dry-run        # Show what would change
+  ut sync ./dist --delete --yes   # Also remove remote files missing locally`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		failed, err := runSync(cmd.Context(), args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error syncing directory: %v\n", err)
+			exit(cmd.Context(), 1)
+		}
+		if failed > 0 {
+			exit(cmd.Context(), 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+
+	syncCmd.Flags().BoolVar(&syncDelete, "delete", false, "Delete remote files that do not exist locally")
+	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "Show the sync plan without changing anything")
+	syncCmd.Flags().BoolVarP(&syncYes, "yes", "y", false, "Delete remote files without asking for confirmation")
+	syncCmd.Flags().IntVarP(&uploadConcurrency, "concurrency", "c", 4, "Number of files uploaded in parallel")
+	syncCmd.Flags().StringArrayVar(&includePatterns, "include", nil, "Only sync files matching this glob (repeatable)")
+	syncCmd.Flags().StringArrayVar(&excludePatterns, "exclude", nil, "Skip files and directories matching this glob (repeatable)")
+}
+
+// syncManifest remembers what was uploaded for each relative path so that
+// unchanged files can be detected without downloading them.
+type syncManifest struct {
+	Files map[string]syncEntry `json:"files"`
+}
+
+type syncEntry struct {
+	Key    string `json:"key"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+type SyncAction struct {
+	Action string `json:"action" yaml:"action"`
+	Name   string `json:"name" yaml:"name"`
+	Size   int64  `json:"size" yaml:"size"`
+	Key    string `json:"key,omitempty" yaml:"key,omitempty"`
+	Error  string `json:"error,omitempty" yaml:"error,omitempty"`
+
+	source uploadSource
+	hash   string
+	oldKey string
+}
+
+func (a SyncAction) csvHeader() []string {
+	return []string{"action", "name", "size", "key", "error"}
+}
+
+func (a SyncAction) csvRow() []string {
+	return []string{a.Action, a.Name, strconv.FormatInt(a.Size, 10), a.Key, a.Error}
+}
+
+func loadSyncManifest(path string) (*syncManifest, error) {
+	manifest := &syncManifest{Files: map[string]syncEntry{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return manifest, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", syncManifestName, err)
+	}
+	if manifest.Files == nil {
+		manifest.Files = map[string]syncEntry{}
+	}
+	return manifest, nil
+}
+
+func (m *syncManifest) save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func runSync(ctx context.Context, dir string) (int, error) {
+	stat, err := os.Stat(dir)
+	if err != nil {
+		return 0, err
+	}
+	if !stat.IsDir() {
+		return 0, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	includes, err := compileGlobs(includePatterns)
+	if err != nil {
+		return 0, err
+	}
+	excludes, err := compileGlobs(excludePatterns)
+	if err != nil {
+		return 0, err
+	}
+	filter, err := newUploadFilter(dir, includes, excludes)
+	if err != nil {
+		return 0, err
+	}
+
+	manifestPath := filepath.Join(dir, syncManifestName)
+	manifest, err := loadSyncManifest(manifestPath)
+	if err != nil {
+		return 0, err
+	}
+
+	local, err := walkUploadDir(dir, filter)
+	if err != nil {
+		return 0, err
+	}
+	localNames := map[string]bool{}
+	for _, src := range local {
+		localNames[src.Name] = true
+	}
+
+	infof("Fetching remote file list...\n")
+	byName := map[string][]uploadthing.FileInfo{}
+	client := newClient(cfg)
+	it := client.Files(defaultPageSize, 0)
+	for it.HasNext() {
+		files, err := it.Next(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list remote files: %w", err)
+		}
+		for _, file := range files {
+			byName[file.Name] = append(byName[file.Name], file)
+		}
+	}
+
+	// Only names that exist locally, or that --delete may remove, are synced.
+	// Excluded and ignored names are left alone on the remote side.
+	synced := func(name string) bool {
+		return localNames[name] || (syncDelete && filter.selects(name))
+	}
+
+	remote := map[string]uploadthing.FileInfo{}
+	var extra []uploadthing.FileInfo
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !synced(name) {
+			continue
+		}
+		keep, copies, err := pickRemote(name, byName[name], manifest)
+		if err != nil {
+			return 0, err
+		}
+		remote[name] = keep
+		extra = append(extra, copies...)
+	}
+
+	actions, unchanged, err := planSync(local, remote, manifest)
+	if err != nil {
+		return 0, err
+	}
+
+	if syncDelete {
+		for _, name := range names {
+			if file, ok := remote[name]; ok && !localNames[name] {
+				extra = append(extra, file)
+			}
+		}
+		sort.SliceStable(extra, func(i, j int) bool { return extra[i].Name < extra[j].Name })
+		for _, file := range extra {
+			actions = append(actions, SyncAction{Action: syncActionDelete, Name: file.Name, Size: file.Size, Key: file.FileKey})
+		}
+	} else if len(extra) > 0 {
+		infof("%d extra remote copies of synced files were left alone (use --delete to remove them).\n", len(extra))
+	}
+
+	if syncDryRun {
+		if len(actions) == 0 {
+			infof("Everything is up to date.\n")
+			return 0, nil
+		}
+		printSyncPlan(actions)
+		if !isTableOutput() {
+			return 0, writeSyncActions(actions)
+		}
+		return 0, nil
+	}
+
+	for name, entry := range unchanged {
+		manifest.Files[name] = entry
+	}
+	if len(actions) == 0 {
+		infof("Everything is up to date.\n")
+		return 0, manifest.save(manifestPath)
+	}
+	if isTableOutput() {
+		printSyncPlan(actions)
+	}
+
+	deletes := countActions(actions, syncActionDelete)
+	if deletes > 0 && !syncYes {
+		if !confirm(fmt.Sprintf("Delete %d remote file(s)? This cannot be undone.", deletes)) {
+			return 0, fmt.Errorf("sync cancelled by user")
+		}
+	}
+
+	failed := executeSync(ctx, client, actions, manifest)
+
+	if err := manifest.save(manifestPath); err != nil {
+		return failed, fmt.Errorf("failed to write %s: %w", syncManifestName, err)
+	}
+
+	if !isTableOutput() {
+		if err := writeSyncActions(actions); err != nil {
+			return failed, err
+		}
+	}
+
+	infof("\nSync finished: %d uploaded, %d deleted, %d failed.\n",
+		countActions(actions, syncActionUpload)+countActions(actions, syncActionReplace)-countFailed(actions, syncActionUpload, syncActionReplace),
+		deletes-countFailed(actions, syncActionDelete),
+		failed)
+	if ctx.Err() != nil {
+		return failed, context.Cause(ctx)
+	}
+	return failed, nil
+}
+
+// pickRemote chooses which of the remote files with the same name the sync
+// manages and returns the others as extra copies. With several copies, the one
+// recorded in the manifest is kept; if the manifest does not name one, the
+// sync refuses to guess which copy to replace or delete.
+func pickRemote(name string, files []uploadthing.FileInfo, manifest *syncManifest) (uploadthing.FileInfo, []uploadthing.FileInfo, error) {
+	if len(files) == 1 {
+		return files[0], nil, nil
+	}
+
+	entry, known := manifest.Files[name]
+	keys := make([]string, 0, len(files))
+	for i, file := range files {
+		if known && file.FileKey == entry.Key {
+			extra := append(append([]uploadthing.FileInfo{}, files[:i]...), files[i+1:]...)
+			return file, extra, nil
+		}
+		keys = append(keys, file.FileKey)
+	}
+	return uploadthing.FileInfo{}, nil, fmt.Errorf("%d remote files are named %s (%s) and %s does not say which one is synced; delete the extra copies with 'ut delete' first",
+		len(files), name, strings.Join(keys, ", "), syncManifestName)
+}
+
+// planSync decides which local files need uploading. A file whose size
+// matches the remote copy is only considered unchanged when the manifest
+// records its hash for that same remote key; without such an entry the remote
+// content cannot be compared, so the file is replaced. It does not change
+// manifest; the verified entries of unchanged files are returned for the
+// caller to record.
+func planSync(local []uploadSource, remote map[string]uploadthing.FileInfo, manifest *syncManifest) ([]SyncAction, map[string]syncEntry, error) {
+	var actions []SyncAction
+	unchanged := map[string]syncEntry{}
+	for _, src := range local {
+		stat, err := os.Stat(src.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		size := stat.Size()
+
+		remoteFile, exists := remote[src.Name]
+		if !exists {
+			actions = append(actions, SyncAction{Action: syncActionUpload, Name: src.Name, Size: size, source: src})
+			continue
+		}
+
+		if remoteFile.Size != size {
+			actions = append(actions, SyncAction{Action: syncActionReplace, Name: src.Name, Size: size, source: src, oldKey: remoteFile.FileKey})
+			continue
+		}
+
+		hash, err := hashFile(src.Path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to hash %s: %w", src.Path, err)
+		}
+
+		entry, known := manifest.Files[src.Name]
+		if !known || entry.Key != remoteFile.FileKey || entry.SHA256 != hash {
+			actions = append(actions, SyncAction{Action: syncActionReplace, Name: src.Name, Size: size, source: src, hash: hash, oldKey: remoteFile.FileKey})
+			continue
+		}
+
+		unchanged[src.Name] = syncEntry{Key: remoteFile.FileKey, Size: size, SHA256: hash}
+	}
+	return actions, unchanged, nil
+}
+
+// executeSync carries out the plan, recording each outcome in its action and
+// every finished upload in manifest. If ctx is canceled, actions that were
+// not started are marked as failed with the cause.
+func executeSync(ctx context.Context, client *uploadthing.Client, actions []SyncAction, manifest *syncManifest) int {
+	var sources []uploadSource
+	byPath := map[string]int{}
+	for i, action := range actions {
+		if action.Action == syncActionUpload || action.Action == syncActionReplace {
+			sources = append(sources, action.source)
+			byPath[action.source.Path] = i
+		}
+	}
+
+	if len(sources) > 0 {
+		recordOut := io.Writer(os.Stdout)
+		if !isTableOutput() {
+			recordOut = io.Discard
+		}
+		results, _, err := runPush(ctx, sources, newUploadReporter(len(sources), recordOut))
+		if err != nil && ctx.Err() == nil {
+			for i := range actions {
+				if actions[i].Action != syncActionDelete {
+					actions[i].Error = err.Error()
+				}
+			}
+			return len(sources)
+		}
+
+		finished := map[string]bool{}
+		for _, result := range results {
+			finished[result.Path] = true
+			action := &actions[byPath[result.Path]]
+			if result.Error != "" {
+				action.Error = result.Error
+				continue
+			}
+			action.Key = result.Key
+
+			hash := action.hash
+			if hash == "" {
+				hash, _ = hashFile(action.source.Path)
+			}
+			manifest.Files[action.Name] = syncEntry{Key: result.Key, Size: result.Size, SHA256: hash}
+
+			if action.oldKey != "" {
+				if err := deleteFile(context.WithoutCancel(ctx), client, action.oldKey); err != nil {
+					infof("Warning: uploaded new %s but failed to delete old copy %s: %v\n", action.Name, action.oldKey, err)
+				}
+			}
+		}
+
+		for _, src := range sources {
+			if action := &actions[byPath[src.Path]]; !finished[src.Path] && action.Error == "" && ctx.Err() != nil {
+				action.Error = "not started: " + context.Cause(ctx).Error()
+			}
+		}
+	}
+
+	for i := range actions {
+		action := &actions[i]
+		if action.Action != syncActionDelete {
+			continue
+		}
+		if ctx.Err() != nil {
+			action.Error = "not started: " + context.Cause(ctx).Error()
+			continue
+		}
+		if err := deleteFile(ctx, client, action.Key); err != nil {
+			action.Error = err.Error()
+			fmt.Fprintf(os.Stderr, "✗ delete %s: %v\n", action.Name, err)
+			continue
+		}
+		infof("✓ deleted %s\n", action.Name)
+		if entry, ok := manifest.Files[action.Name]; ok && entry.Key == action.Key {
+			delete(manifest.Files, action.Name)
+		}
+	}
+
+	failed := 0
+	for _, action := range actions {
+		if action.Error != "" {
+			failed++
+		}
+	}
+	return failed
+}
+
+func printSyncPlan(actions []SyncAction) {
+	symbols := map[string]string{
+		syncActionUpload:  "+",
+		syncActionReplace: "~",
+		syncActionDelete:  "-",
+	}
+	infof("Sync plan:\n")
+	for _, action := range actions {
+		infof("  %s %-40s %s\n", symbols[action.Action], action.Name, formatFileSize(action.Size))
+	}
+	infof("%d to upload, %d to replace, %d to delete.\n\n",
+		countActions(actions, syncActionUpload),
+		countActions(actions, syncActionReplace),
+		countActions(actions, syncActionDelete))
+}
+
+func writeSyncActions(actions []SyncAction) error {
+	records := make([]record, len(actions))
+	for i, action := range actions {
+		records[i] = action
+	}
+	return writeRecords(records...)
+}
+
+func countActions(actions []SyncAction, kind string) int {
+	n := 0
+	for _, action := range actions {
+		if action.Action == kind {
+			n++
+		}
+	}
+	return n
+}
+
+func countFailed(actions []SyncAction, kinds ...string) int {
+	n := 0
+	for _, action := range actions {
+		if action.Error == "" {
+			continue
+		}
+		for _, kind := range kinds {
+			if action.Action == kind {
+				n++
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MhemedAbderrahmen/ut/config"
+	"github.com/MhemedAbderrahmen/ut/uploadthing"
+)
+
+func TestUploadFilterSelects(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, ignoreFileName), []byte("*.log\ncache/\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	excludes, err := compileGlobs([]string{"drafts/**"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter, err := newUploadFilter(root, nil, excludes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		rel  string
+		want bool
+	}{
+		{"index.html", true},
+		{"assets/app.js", true},
+		{"debug.log", false},
+		{"assets/debug.log", false},
+		{"cache/page.html", false},
+		{"assets/cache/page.html", false},
+		{"drafts/post.md", false},
+		{ignoreFileName, false},
+		{syncManifestName, false},
+	}
+	for _, tt := range tests {
+		if got := filter.selects(tt.rel); got != tt.want {
+			t.Errorf("selects(%q) = %v, want %v", tt.rel, got, tt.want)
+		}
+	}
+}
+
+func TestPickRemote(t *testing.T) {
+	a := uploadthing.FileInfo{Name: "a.txt", FileKey: "key-a"}
+	b := uploadthing.FileInfo{Name: "a.txt", FileKey: "key-b"}
+	manifest := &syncManifest{Files: map[string]syncEntry{"a.txt": {Key: "key-b"}}}
+	empty := &syncManifest{Files: map[string]syncEntry{}}
+
+	keep, extra, err := pickRemote("a.txt", []uploadthing.FileInfo{a}, empty)
+	if err != nil || keep != a || len(extra) != 0 {
+		t.Errorf("single copy: got %v, %v, %v", keep, extra, err)
+	}
+
+	keep, extra, err = pickRemote("a.txt", []uploadthing.FileInfo{a, b}, manifest)
+	if err != nil || keep != b || len(extra) != 1 || extra[0] != a {
+		t.Errorf("copy in manifest: got %v, %v, %v; want to keep key-b", keep, extra, err)
+	}
+
+	if _, _, err := pickRemote("a.txt", []uploadthing.FileInfo{a, b}, empty); err == nil {
+		t.Error("duplicates without a manifest entry were not refused")
+	}
+}
+
+// useSyncServer points the configuration at a mock API whose listFiles
+// endpoint returns files and fails the test on any other call.
+func useSyncServer(t *testing.T, files []uploadthing.FileInfo) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v6/listFiles" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(uploadthing.FilesResponse{Files: files})
+	}))
+	t.Cleanup(srv.Close)
+
+	for _, env := range []string{config.TokenEnv, config.SecretEnv, config.APIURLEnv, config.ProfileEnv} {
+		t.Setenv(env, "")
+	}
+	config.SetPathOverride(filepath.Join(t.TempDir(), "config.yml"))
+	config.SetSecretOverride("sk_test_key")
+	config.SetEndpointOverride(srv.URL, "")
+	t.Cleanup(func() {
+		config.SetPathOverride("")
+		config.SetSecretOverride("")
+		config.SetEndpointOverride("", "")
+	})
+}
+
+func TestSyncDryRunWritesNothing(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	useSyncServer(t, []uploadthing.FileInfo{{Name: "index.html", FileKey: "key-1", Size: 5}})
+
+	syncDryRun = true
+	defer func() { syncDryRun = false }()
+
+	if _, err := runSync(context.Background(), dir); err != nil {
+		t.Fatalf("runSync: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, syncManifestName)); !os.IsNotExist(err) {
+		t.Errorf("dry run created %s", syncManifestName)
+	}
+}
+
+func TestPlanSync(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) uploadSource {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return uploadSource{Path: p, Name: name}
+	}
+	hashOf := func(src uploadSource) string {
+		h, err := hashFile(src.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	src := write("app.js", "new!")
+	remoteFile := uploadthing.FileInfo{Name: "app.js", FileKey: "key-1", Size: 4}
+
+	tests := []struct {
+		name          string
+		remote        map[string]uploadthing.FileInfo
+		entry         *syncEntry
+		wantAction    string
+		wantUnchanged bool
+	}{
+		{"not on the remote", map[string]uploadthing.FileInfo{}, nil, syncActionUpload, false},
+		{"different size", map[string]uploadthing.FileInfo{"app.js": {Name: "app.js", FileKey: "key-1", Size: 9}}, nil, syncActionReplace, false},
+		{"same size without manifest entry", map[string]uploadthing.FileInfo{"app.js": remoteFile}, nil, syncActionReplace, false},
+		{"same size, entry for another key", map[string]uploadthing.FileInfo{"app.js": remoteFile}, &syncEntry{Key: "key-0", Size: 4, SHA256: hashOf(src)}, syncActionReplace, false},
+		{"same size, different hash", map[string]uploadthing.FileInfo{"app.js": remoteFile}, &syncEntry{Key: "key-1", Size: 4, SHA256: "old"}, syncActionReplace, false},
+		{"same size, same hash", map[string]uploadthing.FileInfo{"app.js": remoteFile}, &syncEntry{Key: "key-1", Size: 4, SHA256: hashOf(src)}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest := &syncManifest{Files: map[string]syncEntry{}}
+			if tt.entry != nil {
+				manifest.Files["app.js"] = *tt.entry
+			}
+			before := len(manifest.Files)
+
+			actions, unchanged, err := planSync([]uploadSource{src}, tt.remote, manifest)
+			if err != nil {
+				t.Fatalf("planSync: %v", err)
+			}
+
+			gotAction := ""
+			if len(actions) == 1 {
+				gotAction = actions[0].Action
+			} else if len(actions) > 1 {
+				t.Fatalf("got %d actions, want at most 1", len(actions))
+			}
+			if gotAction != tt.wantAction {
+				t.Errorf("action = %q, want %q", gotAction, tt.wantAction)
+			}
+			if _, ok := unchanged["app.js"]; ok != tt.wantUnchanged {
+				t.Errorf("recorded as unchanged = %v, want %v", ok, tt.wantUnchanged)
+			}
+			if len(manifest.Files) != before || (tt.entry != nil && manifest.Files["app.js"] != *tt.entry) {
+				t.Error("planSync changed the manifest")
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/MhemedAbderrahmen/ut/config"
+	"github.com/MhemedAbderrahmen/ut/uploadthing"
+
+	"github.com/spf13/cobra"
+)
+
+// exitUsageWarning is the exit code of 'ut usage' when storage use is at or
+// above --warn-at. It differs from 1 so that scripts can tell a full plan from
+// a failed request.
+const exitUsageWarning = 2
+
+var usageWarnAt string
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show storage usage and plan limits",
+	Long: `Show how much storage your UploadThing account uses, how much of it belongs
+to the current app, how many files were uploaded and the storage limit of your
+plan.
+
+With --warn-at, the command exits with status 2 when the storage used is at or
+above the given percentage of the limit, so that scheduled jobs can alert
+before uploads start failing.
+
+Examples:
+  ut usage                    # Show usage
+  ut usage --warn-at 80%      # Exit with status 2 at 80% of the limit
+  ut usage --format json      # Machine-readable output`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		over, err := showUsage(cmd.Context())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting usage: %v\n", err)
+			exit(cmd.Context(), 1)
+		}
+		if over {
+			os.Exit(exitUsageWarning)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(usageCmd)
+
+	usageCmd.Flags().StringVar(&usageWarnAt, "warn-at", "", "Exit with status 2 when usage reaches this percentage of the limit, e.g. 80%")
+}
+
+type UsageReport struct {
+	TotalBytes    int64   `json:"totalBytes" yaml:"totalBytes"`
+	AppTotalBytes int64   `json:"appTotalBytes" yaml:"appTotalBytes"`
+	FilesUploaded int     `json:"filesUploaded" yaml:"filesUploaded"`
+	LimitBytes    int64   `json:"limitBytes" yaml:"limitBytes"`
+	UsedPercent   float64 `json:"usedPercent" yaml:"usedPercent"`
+}
+
+func (r UsageReport) csvHeader() []string {
+	return []string{"totalBytes", "appTotalBytes", "filesUploaded", "limitBytes", "usedPercent"}
+}
+
+func (r UsageReport) csvRow() []string {
+	return []string{
+		strconv.FormatInt(r.TotalBytes, 10),
+		strconv.FormatInt(r.AppTotalBytes, 10),
+		strconv.Itoa(r.FilesUploaded),
+		strconv.FormatInt(r.LimitBytes, 10),
+		strconv.FormatFloat(r.UsedPercent, 'f', 1, 64),
+	}
+}
+
+func newUsageReport(info *uploadthing.UsageInfo) UsageReport {
+	report := UsageReport{
+		TotalBytes:    info.TotalBytes,
+		AppTotalBytes: info.AppTotalBytes,
+		FilesUploaded: info.FilesUploaded,
+		LimitBytes:    info.LimitBytes,
+	}
+	if info.LimitBytes > 0 {
+		report.UsedPercent = float64(info.TotalBytes) / float64(info.LimitBytes) * 100
+	}
+	return report
+}
+
+// parsePercent accepts a percentage such as "80%" or "80".
+func parsePercent(value string) (float64, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value, "%")), 64)
+	if err != nil || percent <= 0 || percent > 100 {
+		return 0, fmt.Errorf("invalid --warn-at %q (use a percentage between 0 and 100, e.g. 80%%)", value)
+	}
+	return percent, nil
+}
+
+// showUsage prints the usage report and reports whether it is at or above
+// --warn-at.
+func showUsage(ctx context.Context) (bool, error) {
+	var warnAt float64
+	if usageWarnAt != "" {
+		var err error
+		if warnAt, err = parsePercent(usageWarnAt); err != nil {
+			return false, err
+		}
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return false, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	info, err := newClient(cfg).GetUsageInfo(ctx)
+	if err != nil {
+		return false, err
+	}
+	report := newUsageReport(info)
+
+	if !isTableOutput() {
+		if err := writeRecords(report); err != nil {
+			return false, err
+		}
+	} else {
+		if report.LimitBytes > 0 {
+			fmt.Printf("Storage used:   %s of %s (%.1f%%)\n", formatFileSize(report.TotalBytes), formatFileSize(report.LimitBytes), report.UsedPercent)
+		} else {
+			fmt.Printf("Storage used:   %s (no limit)\n", formatFileSize(report.TotalBytes))
+		}
+		fmt.Printf("This app:       %s\n", formatFileSize(report.AppTotalBytes))
+		fmt.Printf("Files uploaded: %d\n", report.FilesUploaded)
+	}
+
+	if warnAt == 0 {
+		return false, nil
+	}
+	if report.LimitBytes == 0 {
+		infof("No storage limit reported; --warn-at was not checked.\n")
+		return false, nil
+	}
+	if report.UsedPercent >= warnAt {
+		fmt.Fprintf(os.Stderr, "Warning: storage usage is at %.1f%% of the limit (--warn-at %s)\n", report.UsedPercent, usageWarnAt)
+		return true, nil
+	}
+	return false, nil
+}
@@ -1,6 +1,21 @@
 package cmd
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
+
+// confirm asks a yes/no question on stderr and reports whether the user
+// answered yes.
+func confirm(question string) bool {
+	defer beginPrompt()()
+
+	infof("%s (y/N): ", question)
+	var response string
+	fmt.Scanln(&response)
+	response = strings.ToLower(response)
+	return response == "y" || response == "yes"
+}
 
 func formatFileSize(bytes int64) string {
 	const unit = 1024
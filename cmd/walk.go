@@ -0,0 +1,349 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+const ignoreFileName = ".utignore"
+
+const (
+	relPathAsName     = "name"
+	relPathAsCustomID = "custom-id"
+)
+
+var (
+	recursive       bool
+	includePatterns []string
+	excludePatterns []string
+	relPathAs       string
+	customIDFormat  string
+)
+
+// uploadSource is a local file to upload together with the name and custom ID
+// it should get on UploadThing.
+type uploadSource struct {
+	Path     string
+	Name     string
+	CustomID string
+}
+
+// customIDData is what a --custom-id template can refer to, e.g.
+// "{{.RelPath}}" or "avatars/{{.Stem}}".
+type customIDData struct {
+	Path    string // local path of the file
+	RelPath string // slash-separated path relative to the pushed directory
+	Name    string // base name of the file
+	Stem    string // base name without the extension
+	Ext     string // extension including the dot
+}
+
+// globPattern is a gitignore-style pattern. Patterns without a slash match the
+// base name at any depth, patterns with a slash match the path relative to the
+// pushed directory, "**" matches across directories and a trailing slash only
+// matches directories.
+type globPattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+func compileGlob(pattern string) (globPattern, error) {
+	var g globPattern
+
+	if strings.HasPrefix(pattern, "!") {
+		g.negate = true
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		g.dirOnly = true
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	if !anchored {
+		sb.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return g, fmt.Errorf("invalid pattern %q: unterminated character class", pattern)
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return g, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	g.re = re
+	return g, nil
+}
+
+func (g globPattern) match(rel string, isDir bool) bool {
+	if g.dirOnly && !isDir {
+		return false
+	}
+	return g.re.MatchString(rel)
+}
+
+func compileGlobs(patterns []string) ([]globPattern, error) {
+	var globs []globPattern
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		g, err := compileGlob(p)
+		if err != nil {
+			return nil, err
+		}
+		globs = append(globs, g)
+	}
+	return globs, nil
+}
+
+// matchAny reports whether rel is selected by globs. Later patterns override
+// earlier ones, so a "!" pattern can re-include a previously matched path.
+func matchAny(globs []globPattern, rel string, isDir bool) bool {
+	matched := false
+	for _, g := range globs {
+		if g.match(rel, isDir) {
+			matched = !g.negate
+		}
+	}
+	return matched
+}
+
+func readIgnoreFile(dir string) ([]string, error) {
+	file, err := os.Open(filepath.Join(dir, ignoreFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+// collectUploadSources expands the push arguments into files to upload,
+// walking directories when --recursive is set.
+func collectUploadSources(args []string) ([]uploadSource, error) {
+	if relPathAs != relPathAsName && relPathAs != relPathAsCustomID {
+		return nil, fmt.Errorf("invalid --relpath-as %q (valid: %s, %s)", relPathAs, relPathAsName, relPathAsCustomID)
+	}
+	if customIDFormat != "" && relPathAs == relPathAsCustomID {
+		return nil, fmt.Errorf("--custom-id cannot be combined with --relpath-as %s", relPathAsCustomID)
+	}
+
+	includes, err := compileGlobs(includePatterns)
+	if err != nil {
+		return nil, err
+	}
+	excludes, err := compileGlobs(excludePatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []uploadSource
+	for _, arg := range args {
+		stat, err := os.Stat(arg)
+		if err != nil || !stat.IsDir() || !recursive {
+			sources = append(sources, uploadSource{Path: arg, Name: filepath.Base(arg)})
+			continue
+		}
+
+		filter, err := newUploadFilter(arg, includes, excludes)
+		if err != nil {
+			return nil, err
+		}
+		dirSources, err := walkUploadDir(arg, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, src := range dirSources {
+			if relPathAs == relPathAsCustomID {
+				src.CustomID = src.Name
+				src.Name = path.Base(src.Name)
+			}
+			sources = append(sources, src)
+		}
+	}
+
+	if customIDFormat != "" {
+		if err := applyCustomIDs(sources, customIDFormat); err != nil {
+			return nil, err
+		}
+	}
+	return sources, nil
+}
+
+// applyCustomIDs sets the custom ID of every source from the --custom-id
+// template. Custom IDs are unique per app, so a template that gives two files
+// the same ID is rejected before anything is uploaded.
+func applyCustomIDs(sources []uploadSource, format string) error {
+	tmpl, err := template.New("custom-id").Parse(format)
+	if err != nil {
+		return fmt.Errorf("invalid --custom-id template: %w", err)
+	}
+
+	used := map[string]string{}
+	for i := range sources {
+		src := &sources[i]
+		name := path.Base(src.Name)
+		ext := path.Ext(name)
+
+		var id strings.Builder
+		err := tmpl.Execute(&id, customIDData{
+			Path:    src.Path,
+			RelPath: src.Name,
+			Name:    name,
+			Stem:    strings.TrimSuffix(name, ext),
+			Ext:     ext,
+		})
+		if err != nil {
+			return fmt.Errorf("invalid --custom-id template: %w", err)
+		}
+		if id.Len() == 0 {
+			return fmt.Errorf("--custom-id template gives %s an empty custom ID", src.Path)
+		}
+		if other, ok := used[id.String()]; ok {
+			return fmt.Errorf("--custom-id %q gives both %s and %s the custom ID %q (use a template such as {{.RelPath}})", format, other, src.Path, id.String())
+		}
+
+		used[id.String()] = src.Path
+		src.CustomID = id.String()
+	}
+	return nil
+}
+
+// uploadFilter selects the paths below a pushed directory, relative to it,
+// that are uploaded: those not skipped by its .utignore file or --exclude and,
+// if --include is given, matching it.
+type uploadFilter struct {
+	ignores  []globPattern
+	includes []globPattern
+	excludes []globPattern
+}
+
+func newUploadFilter(root string, includes, excludes []globPattern) (*uploadFilter, error) {
+	ignorePatterns, err := readIgnoreFile(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ignoreFileName, err)
+	}
+	ignores, err := compileGlobs(ignorePatterns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ignoreFileName, err)
+	}
+	return &uploadFilter{ignores: ignores, includes: includes, excludes: excludes}, nil
+}
+
+// skipsDir reports whether the directory rel and everything below it is
+// skipped.
+func (f *uploadFilter) skipsDir(rel string) bool {
+	return matchAny(f.ignores, rel, true) || matchAny(f.excludes, rel, true)
+}
+
+// selectsFile reports whether the file rel is uploaded, assuming its parent
+// directories are not skipped.
+func (f *uploadFilter) selectsFile(rel string) bool {
+	if path.Base(rel) == ignoreFileName || rel == syncManifestName {
+		return false
+	}
+	if matchAny(f.ignores, rel, false) || matchAny(f.excludes, rel, false) {
+		return false
+	}
+	return len(f.includes) == 0 || matchAny(f.includes, rel, false)
+}
+
+// selects reports whether a file at rel would be uploaded, checking its
+// parent directories as well. It is used for paths that do not exist locally.
+func (f *uploadFilter) selects(rel string) bool {
+	for i := 0; i < len(rel); i++ {
+		if rel[i] == '/' && f.skipsDir(rel[:i]) {
+			return false
+		}
+	}
+	return f.selectsFile(rel)
+}
+
+// walkUploadDir returns the files under root selected by filter, named by
+// their slash-separated path relative to root.
+func walkUploadDir(root string, filter *uploadFilter) ([]uploadSource, error) {
+	var sources []uploadSource
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if filter.skipsDir(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !filter.selectsFile(rel) {
+			return nil
+		}
+
+		sources = append(sources, uploadSource{Path: p, Name: rel})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", root, err)
+	}
+	return sources, nil
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		rel     string
+		isDir   bool
+		want    bool
+	}{
+		// Patterns without a slash match the base name at any depth.
+		{"*.log", "debug.log", false, true},
+		{"*.log", "logs/debug.log", false, true},
+		{"*.log", "debug.log.txt", false, false},
+		{"*.log", "logs", true, false},
+		{"?.txt", "a.txt", false, true},
+		{"?.txt", "ab.txt", false, false},
+		{"*", "a/b", false, true},
+
+		// A slash anchors the pattern to the pushed directory.
+		{"/build", "build", true, true},
+		{"/build", "src/build", true, false},
+		{"docs/*.md", "docs/a.md", false, true},
+		{"docs/*.md", "src/docs/a.md", false, false},
+		{"docs/*.md", "docs/sub/a.md", false, false},
+
+		// "**" matches across directories.
+		{"**/*.png", "a.png", false, true},
+		{"**/*.png", "img/icons/a.png", false, true},
+		{"assets/**", "assets/a/b.css", false, true},
+		{"assets/**", "other/assets/a.css", false, false},
+		{"a/**/b", "a/b", false, true},
+		{"a/**/b", "a/x/y/b", false, true},
+		{"a/**/b", "a/x/yb", false, false},
+
+		// Character classes.
+		{"file[0-9].txt", "file7.txt", false, true},
+		{"file[0-9].txt", "filex.txt", false, false},
+		{"file[!0-9].txt", "filex.txt", false, true},
+		{"file[!0-9].txt", "file7.txt", false, false},
+		{"[ab].txt", "b.txt", false, true},
+
+		// A trailing slash only matches directories.
+		{"cache/", "cache", true, true},
+		{"cache/", "cache", false, false},
+		{"cache/", "src/cache", true, true},
+
+		// Other characters are literal.
+		{"a+b(1).txt", "a+b(1).txt", false, true},
+		{"a.txt", "abtxt", false, false},
+	}
+
+	for _, tt := range tests {
+		g, err := compileGlob(tt.pattern)
+		if err != nil {
+			t.Fatalf("compileGlob(%q): %v", tt.pattern, err)
+		}
+		if got := g.match(tt.rel, tt.isDir); got != tt.want {
+			t.Errorf("%q.match(%q, dir=%v) = %v, want %v", tt.pattern, tt.rel, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestCompileGlobErrors(t *testing.T) {
+	for _, pattern := range []string{"file[0-9.txt", "[]"} {
+		if _, err := compileGlob(pattern); err == nil {
+			t.Errorf("compileGlob(%q) succeeded, want an error", pattern)
+		}
+	}
+}
+
+func TestMatchAnyNegation(t *testing.T) {
+	globs, err := compileGlobs([]string{"*.log", "!keep.log", "", "  "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(globs) != 2 {
+		t.Fatalf("compiled %d patterns, want blank ones skipped", len(globs))
+	}
+
+	tests := []struct {
+		rel  string
+		want bool
+	}{
+		{"debug.log", true},
+		{"keep.log", false},
+		{"logs/keep.log", false},
+		{"readme.md", false},
+	}
+	for _, tt := range tests {
+		if got := matchAny(globs, tt.rel, false); got != tt.want {
+			t.Errorf("matchAny(%q) = %v, want %v", tt.rel, got, tt.want)
+		}
+	}
+
+	// A later pattern overrides an earlier negation.
+	globs, _ = compileGlobs([]string{"!keep.log", "*.log"})
+	if !matchAny(globs, "keep.log", false) {
+		t.Error("later *.log did not override the earlier !keep.log")
+	}
+}
+
+func TestApplyCustomIDs(t *testing.T) {
+	sources := func() []uploadSource {
+		return []uploadSource{
+			{Path: "dist/img/logo.png", Name: "img/logo.png"},
+			{Path: "dist/index.html", Name: "index.html"},
+			{Path: "dist/archive.tar.gz", Name: "archive.tar.gz"},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		format  string
+		want    []string
+		wantErr string
+	}{
+		{"relative path", "{{.RelPath}}", []string{"img/logo.png", "index.html", "archive.tar.gz"}, ""},
+		{"stem with prefix", "site/{{.Stem}}", []string{"site/logo", "site/index", "site/archive.tar"}, ""},
+		{"name and extension", "{{.Name}}:{{.Ext}}", []string{"logo.png:.png", "index.html:.html", "archive.tar.gz:.gz"}, ""},
+		{"local path", "{{.Path}}", []string{"dist/img/logo.png", "dist/index.html", "dist/archive.tar.gz"}, ""},
+		{"duplicate fixed ID", "avatar", nil, `custom ID "avatar"`},
+		{"duplicate after expansion", "{{if .Ext}}file{{end}}", nil, "gives both"},
+		{"empty result", `{{if eq .Stem "index"}}{{else}}{{.Stem}}{{end}}`, nil, "empty custom ID"},
+		{"unknown field", "{{.Missing}}", nil, "invalid --custom-id template"},
+		{"syntax error", "{{.RelPath", nil, "invalid --custom-id template"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srcs := sources()
+			err := applyCustomIDs(srcs, tt.format)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyCustomIDs: %v", err)
+			}
+			for i, src := range srcs {
+				if src.CustomID != tt.want[i] {
+					t.Errorf("custom ID of %s = %q, want %q", src.Path, src.CustomID, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCustomIDConflictsWithRelPathAsCustomID(t *testing.T) {
+	customIDFormat, relPathAs = "{{.RelPath}}", relPathAsCustomID
+	defer func() { customIDFormat, relPathAs = "", relPathAsName }()
+
+	_, err := collectUploadSources([]string{t.TempDir()})
+	if err == nil || !strings.Contains(err.Error(), "cannot be combined") {
+		t.Errorf("error = %v, want --custom-id and --relpath-as custom-id to conflict", err)
+	}
+}
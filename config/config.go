@@ -1,46 +1,325 @@
 package config
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
 	"github.com/mitchellh/go-homedir"
-	"gopkg.in/yaml.v2" // Or encoding/json
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultProfile is the profile stored in the top-level appname/secretkey
+// fields, which is where configs written before profiles existed keep them.
+const DefaultProfile = "default"
+
+// ProfileEnv selects the active profile when --profile is not given.
+const ProfileEnv = "UT_PROFILE"
+
+// PathEnv selects the config file when --config is not given.
+const PathEnv = "UT_CONFIG"
+
+// Sources the config file location can be resolved from, in order of precedence.
+const (
+	PathSourceFlag    = "--config flag"
+	PathSourceEnv     = PathEnv + " environment variable"
+	PathSourcePointer = "set-config-path"
+	PathSourceDefault = "default location"
+)
+
+const (
+	legacyDirName   = ".ut-cli"
+	xdgDirName      = "ut"
+	configFileName  = "config.yml"
+	pointerFileName = "config-path"
+)
+
+const (
+	SecretEnv = "UPLOADTHING_SECRET"
+	TokenEnv  = "UPLOADTHING_TOKEN"
+)
+
+// Environment variables overriding the endpoints of the active profile.
+const (
+	APIURLEnv   = "UT_API_URL"
+	FileHostEnv = "UT_FILE_HOST"
+)
+
+// Sources a secret key can be resolved from, in order of precedence.
+const (
+	SourceFlag      = "--secret flag"
+	SourceSecretEnv = SecretEnv + " environment variable"
+	SourceTokenEnv  = TokenEnv + " environment variable"
+	SourceFile      = "config file"
 )
 
 type Config struct {
-	AppName   string `yaml:"appname"`
-	SecretKey string `yaml:"secretkey"`
+	AppName   string   `yaml:"appname"`
+	SecretKey string   `yaml:"secretkey"`
+	Regions   []string `yaml:"-"`
+	Profile   string   `yaml:"-"`
+	Source    string   `yaml:"-"`
+	Store     string   `yaml:"-"`
+	APIURL    string   `yaml:"-"`
+	FileHost  string   `yaml:"-"`
+
+	ContentTypes map[string]string `yaml:"-"`
+}
+
+// Token is the decoded form of UPLOADTHING_TOKEN, a base64-encoded JSON
+// object as issued in the UploadThing dashboard and used by the JS SDK.
+type Token struct {
+	APIKey  string   `json:"apiKey"`
+	AppID   string   `json:"appId"`
+	Regions []string `json:"regions"`
+}
+
+// Profile holds the settings of one app. Store names the secret store holding
+// the secret key; when empty the key is kept in plain text in SecretKey.
+// APIURL and FileHost override the default UploadThing endpoints; FileHost may
+// contain {appId}, which is replaced with AppName.
+type Profile struct {
+	AppName   string `yaml:"appname,omitempty"`
+	SecretKey string `yaml:"secretkey,omitempty"`
+	Store     string `yaml:"store,omitempty"`
+	APIURL    string `yaml:"apiurl,omitempty"`
+	FileHost  string `yaml:"filehost,omitempty"`
+}
+
+// File is the on-disk layout of config.yml.
+type File struct {
+	AppName   string             `yaml:"appname,omitempty"`
+	SecretKey string             `yaml:"secretkey,omitempty"`
+	Store     string             `yaml:"store,omitempty"`
+	APIURL    string             `yaml:"apiurl,omitempty"`
+	FileHost  string             `yaml:"filehost,omitempty"`
+	Current   string             `yaml:"current,omitempty"`
+	Profiles  map[string]Profile `yaml:"profiles,omitempty"`
+
+	// ContentTypes maps lower-case file extensions such as ".webp" to the
+	// Content-Type used when uploading them, for every profile.
+	ContentTypes map[string]string `yaml:"contenttypes,omitempty"`
 }
 
 var (
-	cachedConfig *Config
-	configMutex  sync.Mutex
+	cachedConfig    *Config
+	configMutex     sync.Mutex
+	loadMutex       sync.Mutex
+	profileOverride string
+	secretOverride  string
+	pathOverride    string
+	apiURLOverride  string
+	hostOverride    string
 )
 
 var (
-	ErrConfigNotFound = errors.New("configuration file not found")
-	ErrAPIKeyMissing  = errors.New("api key not set")
+	ErrConfigNotFound  = errors.New("configuration file not found")
+	ErrAPIKeyMissing   = errors.New("api key not set")
+	ErrProfileNotFound = errors.New("profile not found")
+	ErrInvalidToken    = errors.New("invalid UPLOADTHING_TOKEN")
+	ErrInvalidProfile  = errors.New("invalid profile name")
 )
 
-func LoadConfig() (*Config, error) {
+// SetSecretOverride makes LoadConfig use secret ahead of every other source.
+func SetSecretOverride(secret string) {
 	configMutex.Lock()
 	defer configMutex.Unlock()
 
-	if cachedConfig != nil {
-		return cachedConfig, nil
+	secretOverride = secret
+	cachedConfig = nil
+}
+
+// SetEndpointOverride makes LoadConfig use apiURL and fileHost, when not
+// empty, ahead of the environment and the config file.
+func SetEndpointOverride(apiURL, fileHost string) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	apiURLOverride = apiURL
+	hostOverride = fileHost
+	cachedConfig = nil
+}
+
+// DecodeToken decodes an UPLOADTHING_TOKEN value.
+func DecodeToken(value string) (*Token, error) {
+	value = strings.TrimSpace(value)
+
+	var data []byte
+	var err error
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		data, err = enc.DecodeString(value)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: not valid base64", ErrInvalidToken)
+	}
+
+	var token Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if strings.TrimSpace(token.APIKey) == "" {
+		return nil, fmt.Errorf("%w: apiKey is missing", ErrInvalidToken)
+	}
+	return &token, nil
+}
+
+// ValidateProfileName rejects profile names that are empty or could escape
+// the secret store directory, where a profile's name is used as a file name.
+func ValidateProfileName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("%w: name cannot be empty", ErrInvalidProfile)
 	}
+	if strings.ContainsAny(name, "/\\\x00") || strings.Contains(name, "..") {
+		return fmt.Errorf("%w %q: it cannot contain path separators or \"..\"", ErrInvalidProfile, name)
+	}
+	return nil
+}
+
+// SetProfileOverride makes LoadConfig use the named profile regardless of the
+// environment and the profile selected in the config file.
+func SetProfileOverride(name string) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	profileOverride = name
+	cachedConfig = nil
+}
+
+// SetPathOverride makes every command read and write the config file at path.
+func SetPathOverride(path string) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	pathOverride = path
+	cachedConfig = nil
+}
 
+// baseDir is the directory holding the default config file and the pointer
+// written by set-config-path. ~/.ut-cli is kept when it already exists;
+// otherwise $XDG_CONFIG_HOME/ut is used when XDG_CONFIG_HOME is set.
+func baseDir() (string, error) {
 	home, err := homedir.Dir()
 	if err != nil {
-		return nil, fmt.Errorf("unable to find home directory: %w", ErrConfigNotFound)
+		return "", fmt.Errorf("unable to find home directory: %w", err)
 	}
 
-	configFile := filepath.Join(home, ".ut-cli", "config.yml") // Or config.json
+	legacyDir := filepath.Join(home, legacyDirName)
+	if _, err := os.Stat(legacyDir); err == nil {
+		return legacyDir, nil
+	}
+
+	if xdg := strings.TrimSpace(os.Getenv("XDG_CONFIG_HOME")); xdg != "" && filepath.IsAbs(xdg) {
+		return filepath.Join(xdg, xdgDirName), nil
+	}
+	return legacyDir, nil
+}
+
+func pointerPath() (string, error) {
+	dir, err := baseDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, pointerFileName), nil
+}
+
+func expandPath(path string) (string, error) {
+	expanded, err := homedir.Expand(strings.TrimSpace(path))
+	if err != nil {
+		return "", fmt.Errorf("invalid config path %q: %w", path, err)
+	}
+	return filepath.Abs(expanded)
+}
+
+// ResolvePath returns the config file location and where it came from: the
+// --config flag, UT_CONFIG, the path saved by SetPersistedPath, or the default
+// location.
+func ResolvePath() (string, string, error) {
+	configMutex.Lock()
+	override := pathOverride
+	configMutex.Unlock()
+
+	if override != "" {
+		path, err := expandPath(override)
+		return path, PathSourceFlag, err
+	}
+
+	if env := strings.TrimSpace(os.Getenv(PathEnv)); env != "" {
+		path, err := expandPath(env)
+		return path, PathSourceEnv, err
+	}
+
+	pointer, err := pointerPath()
+	if err != nil {
+		return "", "", err
+	}
+	if data, err := os.ReadFile(pointer); err == nil {
+		if saved := strings.TrimSpace(string(data)); saved != "" {
+			path, err := expandPath(saved)
+			return path, PathSourcePointer, err
+		}
+	}
+
+	dir, err := baseDir()
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Join(dir, configFileName), PathSourceDefault, nil
+}
+
+func Path() (string, error) {
+	path, _, err := ResolvePath()
+	return path, err
+}
+
+// SetPersistedPath saves path as the config file location for future runs.
+// An empty path removes the saved location.
+func SetPersistedPath(path string) (string, error) {
+	pointer, err := pointerPath()
+	if err != nil {
+		return "", err
+	}
+
+	if path == "" {
+		if err := os.Remove(pointer); err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("unable to remove saved config path: %w", err)
+		}
+		return "", nil
+	}
+
+	absPath, err := expandPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(pointer), 0700); err != nil {
+		return "", fmt.Errorf("unable to create config directory: %w", err)
+	}
+	if err := os.WriteFile(pointer, []byte(absPath+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("unable to save config path: %w", err)
+	}
+
+	configMutex.Lock()
+	cachedConfig = nil
+	configMutex.Unlock()
+
+	return absPath, nil
+}
+
+// Read loads the config file. It returns ErrConfigNotFound if it does not exist.
+func Read() (*File, error) {
+	configFile, err := Path()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, ErrConfigNotFound)
+	}
 
 	data, err := os.ReadFile(configFile)
 	if err != nil {
@@ -50,15 +329,297 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("unable to read config file: %w", err)
 	}
 
-	var cfg Config
-	err = yaml.Unmarshal(data, &cfg) // Or json.Unmarshal
-	if err != nil {
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
 		return nil, fmt.Errorf("unable to unmarshal config: %w", err)
 	}
+	return &f, nil
+}
+
+// ReadOrEmpty is like Read but returns an empty File if none exists yet.
+func ReadOrEmpty() (*File, error) {
+	f, err := Read()
+	if errors.Is(err, ErrConfigNotFound) {
+		return &File{}, nil
+	}
+	return f, err
+}
+
+// Write saves f to the config file, creating its directory if needed.
+func Write(f *File) error {
+	configFile, err := Path()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(configFile), 0700); err != nil {
+		return fmt.Errorf("unable to create config directory: %w", err)
+	}
+
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return fmt.Errorf("unable to marshal config: %w", err)
+	}
+
+	if err := os.WriteFile(configFile, data, 0600); err != nil {
+		return fmt.Errorf("unable to write config file: %w", err)
+	}
+
+	configMutex.Lock()
+	cachedConfig = nil
+	configMutex.Unlock()
+
+	return nil
+}
+
+// ActiveProfile returns the profile selected by --profile, then UT_PROFILE,
+// then the current profile stored in f, falling back to DefaultProfile.
+func ActiveProfile(f *File) string {
+	configMutex.Lock()
+	override := profileOverride
+	configMutex.Unlock()
+
+	if override != "" {
+		return override
+	}
+	if env := strings.TrimSpace(os.Getenv(ProfileEnv)); env != "" {
+		return env
+	}
+	if f != nil && f.Current != "" {
+		return f.Current
+	}
+	return DefaultProfile
+}
+
+func (f *File) Profile(name string) (Profile, bool) {
+	if name == DefaultProfile {
+		p := Profile{
+			AppName:   f.AppName,
+			SecretKey: f.SecretKey,
+			Store:     f.Store,
+			APIURL:    f.APIURL,
+			FileHost:  f.FileHost,
+		}
+		return p, true
+	}
+	p, ok := f.Profiles[name]
+	return p, ok
+}
+
+func (f *File) SetProfile(name string, p Profile) {
+	if name == DefaultProfile {
+		f.AppName = p.AppName
+		f.SecretKey = p.SecretKey
+		f.Store = p.Store
+		f.APIURL = p.APIURL
+		f.FileHost = p.FileHost
+		return
+	}
+	if f.Profiles == nil {
+		f.Profiles = map[string]Profile{}
+	}
+	f.Profiles[name] = p
+}
+
+func (f *File) RemoveProfile(name string) bool {
+	if name == DefaultProfile {
+		p, _ := f.Profile(DefaultProfile)
+		f.SetProfile(DefaultProfile, Profile{})
+		return p != Profile{}
+	}
+	if _, ok := f.Profiles[name]; !ok {
+		return false
+	}
+	delete(f.Profiles, name)
+	if f.Current == name {
+		f.Current = ""
+	}
+	return true
+}
+
+// ProfileNames returns the configured profiles in sorted order. The default
+// profile is included only when it holds any settings.
+func (f *File) ProfileNames() []string {
+	var names []string
+	if p, _ := f.Profile(DefaultProfile); p != (Profile{}) {
+		names = append(names, DefaultProfile)
+	}
+	for name := range f.Profiles {
+		if name != DefaultProfile {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveSettings resolves everything but the secret key: the app name and
+// endpoints from the config file profile, UPLOADTHING_TOKEN, the UT_API_URL
+// and UT_FILE_HOST environment variables and the --api-url and --file-host
+// flags. It never reads a secret store, so commands that need no credentials
+// can use it without prompting.
+func ResolveSettings() (*Config, error) {
+	cfg, _, _, err := resolveSettings()
+	return cfg, err
+}
+
+func resolveSettings() (*Config, Profile, *Token, error) {
+	var (
+		name    string
+		profile Profile
+	)
+	f, err := Read()
+	switch {
+	case err == nil:
+		name = ActiveProfile(f)
+		if err := ValidateProfileName(name); err != nil {
+			return nil, profile, nil, err
+		}
+		p, ok := f.Profile(name)
+		if !ok {
+			return nil, profile, nil, fmt.Errorf("profile %q: %w", name, ErrProfileNotFound)
+		}
+		profile = p
+	case errors.Is(err, ErrConfigNotFound):
+	default:
+		return nil, profile, nil, err
+	}
+
+	cfg, token, err := profileSettings(name, profile)
+	if err != nil {
+		return nil, profile, nil, err
+	}
+	if f != nil {
+		cfg.ContentTypes = f.ContentTypes
+	}
+	return cfg, profile, token, nil
+}
+
+// ProfileSettings is like ResolveSettings for profile p named name, which
+// need not be saved yet: UPLOADTHING_TOKEN, the environment and the flags
+// are layered over p's settings. Commands that set up a profile use it to
+// talk to the endpoints the profile will use.
+func ProfileSettings(name string, p Profile) (*Config, error) {
+	cfg, _, err := profileSettings(name, p)
+	return cfg, err
+}
+
+func profileSettings(name string, p Profile) (*Config, *Token, error) {
+	configMutex.Lock()
+	apiURL, fileHost := apiURLOverride, hostOverride
+	configMutex.Unlock()
+
+	cfg := &Config{
+		AppName:  p.AppName,
+		Profile:  name,
+		Store:    p.Store,
+		APIURL:   p.APIURL,
+		FileHost: p.FileHost,
+	}
+
+	var token *Token
+	if value := os.Getenv(TokenEnv); strings.TrimSpace(value) != "" {
+		var err error
+		token, err = DecodeToken(value)
+		if err != nil {
+			return nil, nil, err
+		}
+		cfg.Regions = token.Regions
+		if token.AppID != "" {
+			cfg.AppName = token.AppID
+		}
+	}
+
+	for _, value := range []string{os.Getenv(APIURLEnv), apiURL} {
+		if value = strings.TrimSpace(value); value != "" {
+			cfg.APIURL = value
+		}
+	}
+	for _, value := range []string{os.Getenv(FileHostEnv), fileHost} {
+		if value = strings.TrimSpace(value); value != "" {
+			cfg.FileHost = value
+		}
+	}
+
+	return cfg, token, nil
+}
+
+// Resolve layers the configuration sources: the config file profile, then
+// UPLOADTHING_TOKEN, then UPLOADTHING_SECRET, then the --secret flag. Later
+// sources override the secret key of earlier ones; Source reports which one
+// won. A secret kept in a secret store is only read when no other source
+// provides one. Unlike LoadConfig it does not fail when no secret key is found.
+func Resolve() (*Config, error) {
+	configMutex.Lock()
+	override := secretOverride
+	configMutex.Unlock()
+
+	cfg, profile, token, err := resolveSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	if token != nil {
+		cfg.SecretKey = token.APIKey
+		cfg.Source = SourceTokenEnv
+	}
+
+	if value := strings.TrimSpace(os.Getenv(SecretEnv)); value != "" {
+		cfg.SecretKey = value
+		cfg.Source = SourceSecretEnv
+	}
+
+	if strings.TrimSpace(override) != "" {
+		cfg.SecretKey = strings.TrimSpace(override)
+		cfg.Source = SourceFlag
+	}
+
+	if cfg.Source == "" && cfg.Profile != "" {
+		secret, err := LoadSecret(cfg.Profile, profile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.SecretKey = secret
+		if strings.TrimSpace(secret) != "" {
+			cfg.Source = SourceFile
+			if profile.Store != "" {
+				cfg.Source = profile.Store + " secret store"
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// LoadConfig resolves the configuration once and caches it, failing when no
+// secret key is found. Concurrent callers wait for the first one, so a secret
+// store passphrase is asked for at most once at a time.
+func LoadConfig() (*Config, error) {
+	loadMutex.Lock()
+	defer loadMutex.Unlock()
+
+	configMutex.Lock()
+	cached := cachedConfig
+	configMutex.Unlock()
+
+	if cached != nil {
+		return cached, nil
+	}
+
+	cfg, err := Resolve()
+	if err != nil {
+		return nil, err
+	}
 	if strings.TrimSpace(cfg.SecretKey) == "" {
+		if cfg.Profile == "" {
+			return nil, ErrConfigNotFound
+		}
 		return nil, ErrAPIKeyMissing
 	}
 
-	cachedConfig = &cfg
-	return cachedConfig, nil
+	configMutex.Lock()
+	cachedConfig = cfg
+	configMutex.Unlock()
+
+	return cfg, nil
 }
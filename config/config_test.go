@@ -0,0 +1,286 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zalando/go-keyring"
+)
+
+// useTempConfig points the package at a config file in a temporary directory
+// and clears every override and environment source of settings.
+func useTempConfig(t *testing.T) string {
+	t.Helper()
+	for _, env := range []string{ProfileEnv, SecretEnv, TokenEnv, PassphraseEnv, APIURLEnv, FileHostEnv} {
+		t.Setenv(env, "")
+	}
+	path := filepath.Join(t.TempDir(), "config.yml")
+	SetPathOverride(path)
+	SetProfileOverride("")
+	SetSecretOverride("")
+	SetEndpointOverride("", "")
+	t.Cleanup(func() { SetPathOverride("") })
+	return path
+}
+
+func TestValidateProfileName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"default", false},
+		{"staging", false},
+		{"my-app.prod_2", false},
+		{"", true},
+		{"  ", true},
+		{"..", true},
+		{"../../x", true},
+		{"a..b", true},
+		{"a/b", true},
+		{`a\b`, true},
+		{"a\x00b", true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateProfileName(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateProfileName(%q) = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidProfile) {
+			t.Errorf("ValidateProfileName(%q) = %v, want ErrInvalidProfile", tt.name, err)
+		}
+	}
+}
+
+func TestFileStoreRejectsUnsafeProfile(t *testing.T) {
+	store := fileStore{dir: t.TempDir()}
+	t.Setenv(PassphraseEnv, "passphrase")
+
+	if err := store.Set("../escape", "sk_test"); !errors.Is(err, ErrInvalidProfile) {
+		t.Errorf("Set = %v, want ErrInvalidProfile", err)
+	}
+	if _, err := store.Get("../escape"); !errors.Is(err, ErrInvalidProfile) {
+		t.Errorf("Get = %v, want ErrInvalidProfile", err)
+	}
+}
+
+func TestLoadConfigAsksForPassphraseOnce(t *testing.T) {
+	useTempConfig(t)
+
+	t.Setenv(PassphraseEnv, "passphrase")
+	f := &File{}
+	if err := StoreSecret(f, DefaultProfile, StoreFile, "sk_from_file"); err != nil {
+		t.Fatalf("StoreSecret: %v", err)
+	}
+	if err := Write(f); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	t.Setenv(PassphraseEnv, "")
+
+	var prompts atomic.Int32
+	PassphraseFunc = func(confirm bool) (string, error) {
+		prompts.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		return "passphrase", nil
+	}
+	t.Cleanup(func() { PassphraseFunc = nil })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Errorf("LoadConfig: %v", err)
+				return
+			}
+			if cfg.SecretKey != "sk_from_file" {
+				t.Errorf("SecretKey = %q, want sk_from_file", cfg.SecretKey)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := prompts.Load(); n != 1 {
+		t.Errorf("passphrase asked for %d times, want 1", n)
+	}
+}
+
+func TestDecodeToken(t *testing.T) {
+	// "???>>>" makes the standard and URL-safe encodings differ.
+	payload := `{"apiKey":"sk_live_abc","appId":"app???>>>","regions":["sea1"]}`
+	want := &Token{APIKey: "sk_live_abc", AppID: "app???>>>", Regions: []string{"sea1"}}
+	if base64.StdEncoding.EncodeToString([]byte(payload)) == base64.URLEncoding.EncodeToString([]byte(payload)) {
+		t.Fatal("payload does not exercise the URL-safe alphabet")
+	}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"standard", base64.StdEncoding.EncodeToString([]byte(payload))},
+		{"standard without padding", base64.RawStdEncoding.EncodeToString([]byte(payload))},
+		{"URL-safe", base64.URLEncoding.EncodeToString([]byte(payload))},
+		{"URL-safe without padding", base64.RawURLEncoding.EncodeToString([]byte(payload))},
+		{"surrounding whitespace", "  " + base64.StdEncoding.EncodeToString([]byte(payload)) + "\n"},
+	}
+	for _, tt := range tests {
+		got, err := DecodeToken(tt.value)
+		if err != nil {
+			t.Errorf("%s: DecodeToken: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: DecodeToken = %+v, want %+v", tt.name, got, want)
+		}
+	}
+
+	invalid := []struct {
+		name  string
+		value string
+	}{
+		{"not base64", "not a token!"},
+		{"not JSON", base64.StdEncoding.EncodeToString([]byte("apiKey=sk_live_abc"))},
+		{"missing apiKey", base64.StdEncoding.EncodeToString([]byte(`{"appId":"app"}`))},
+		{"blank apiKey", base64.StdEncoding.EncodeToString([]byte(`{"apiKey":"  ","appId":"app"}`))},
+	}
+	for _, tt := range invalid {
+		if _, err := DecodeToken(tt.value); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: DecodeToken error = %v, want ErrInvalidToken", tt.name, err)
+		}
+	}
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	token := base64.StdEncoding.EncodeToString([]byte(`{"apiKey":"sk_token","appId":"token-app"}`))
+
+	tests := []struct {
+		name       string
+		file       bool
+		token      string
+		secret     string
+		flag       string
+		wantKey    string
+		wantSource string
+		wantApp    string
+	}{
+		{"nothing", false, "", "", "", "", "", ""},
+		{"file", true, "", "", "", "sk_file", SourceFile, "file-app"},
+		{"token over file", true, token, "", "", "sk_token", SourceTokenEnv, "token-app"},
+		{"secret over token", true, token, "sk_secret", "", "sk_secret", SourceSecretEnv, "token-app"},
+		{"flag over secret", true, token, "sk_secret", "sk_flag", "sk_flag", SourceFlag, "token-app"},
+		{"flag without file", false, "", "", "sk_flag", "sk_flag", SourceFlag, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTempConfig(t)
+			if tt.file {
+				f := &File{}
+				f.SetProfile(DefaultProfile, Profile{AppName: "file-app", SecretKey: "sk_file"})
+				if err := Write(f); err != nil {
+					t.Fatalf("Write: %v", err)
+				}
+			}
+			t.Setenv(TokenEnv, tt.token)
+			t.Setenv(SecretEnv, tt.secret)
+			SetSecretOverride(tt.flag)
+			defer SetSecretOverride("")
+
+			cfg, err := Resolve()
+			if err != nil {
+				t.Fatalf("Resolve: %v", err)
+			}
+			if cfg.SecretKey != tt.wantKey || cfg.Source != tt.wantSource || cfg.AppName != tt.wantApp {
+				t.Errorf("Resolve = key %q, source %q, app %q; want %q, %q, %q",
+					cfg.SecretKey, cfg.Source, cfg.AppName, tt.wantKey, tt.wantSource, tt.wantApp)
+			}
+		})
+	}
+}
+
+func TestResolveInvalidToken(t *testing.T) {
+	useTempConfig(t)
+	t.Setenv(TokenEnv, "not a token!")
+	if _, err := Resolve(); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Resolve error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestLoadConfigWithoutSecret(t *testing.T) {
+	useTempConfig(t)
+	if _, err := LoadConfig(); !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("LoadConfig without a config file = %v, want ErrConfigNotFound", err)
+	}
+
+	f := &File{}
+	f.SetProfile(DefaultProfile, Profile{AppName: "app"})
+	if err := Write(f); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(); !errors.Is(err, ErrAPIKeyMissing) {
+		t.Errorf("LoadConfig without a secret key = %v, want ErrAPIKeyMissing", err)
+	}
+}
+
+func TestKeyringSecretsAreScopedToConfigFile(t *testing.T) {
+	keyring.MockInit()
+
+	first, second := useTempConfig(t), filepath.Join(t.TempDir(), "config.yml")
+	for _, tt := range []struct{ path, secret string }{{first, "sk_first"}, {second, "sk_second"}} {
+		SetPathOverride(tt.path)
+		f := &File{}
+		if err := StoreSecret(f, "staging", StoreKeyring, tt.secret); err != nil {
+			t.Fatalf("StoreSecret(%s): %v", tt.path, err)
+		}
+	}
+
+	for _, tt := range []struct{ path, want string }{{first, "sk_first"}, {second, "sk_second"}} {
+		SetPathOverride(tt.path)
+		got, err := LoadSecret("staging", Profile{Store: StoreKeyring})
+		if err != nil || got != tt.want {
+			t.Errorf("LoadSecret for %s = %q, %v; want %q", tt.path, got, err, tt.want)
+		}
+	}
+
+	SetPathOverride(first)
+	if err := DeleteSecret("staging", Profile{Store: StoreKeyring}); err != nil {
+		t.Fatalf("DeleteSecret: %v", err)
+	}
+	SetPathOverride(second)
+	if got, err := LoadSecret("staging", Profile{Store: StoreKeyring}); err != nil || got != "sk_second" {
+		t.Errorf("deleting the first config's secret affected the second: %q, %v", got, err)
+	}
+}
+
+func TestKeyringReadsLegacyEntryForDefaultConfig(t *testing.T) {
+	keyring.MockInit()
+	if err := keyring.Set(keyringService, "default", "sk_legacy"); err != nil {
+		t.Fatal(err)
+	}
+
+	store := keyringStore{configFile: "/home/u/.ut-cli/config.yml", legacy: true}
+	if got, err := store.Get("default"); err != nil || got != "sk_legacy" {
+		t.Errorf("Get = %q, %v; want the legacy entry", got, err)
+	}
+	if _, err := (keyringStore{configFile: "/other/config.yml"}).Get("default"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("another config file read the legacy entry: %v", err)
+	}
+
+	if err := store.Set("default", "sk_new"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keyring.Get(keyringService, "default"); !errors.Is(err, keyring.ErrNotFound) {
+		t.Errorf("legacy entry not removed after Set: %v", err)
+	}
+	if got, _ := store.Get("default"); got != "sk_new" {
+		t.Errorf("Get after Set = %q, want sk_new", got)
+	}
+}
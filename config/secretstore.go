@@ -0,0 +1,303 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/zalando/go-keyring"
+)
+
+// Secret stores a profile's secret key can be kept in.
+const (
+	StorePlain   = "plain"
+	StoreKeyring = "keyring"
+	StoreFile    = "file"
+)
+
+// PassphraseEnv supplies the passphrase for the encrypted file store without
+// prompting, for headless use.
+const PassphraseEnv = "UT_SECRET_PASSPHRASE"
+
+const keyringService = "ut-cli"
+
+var ErrSecretNotFound = errors.New("secret not found in store")
+
+// PassphraseFunc asks the user for the passphrase of the encrypted file store.
+// confirm is set when a new secret is being encrypted. The cmd package wires
+// this to a terminal prompt.
+var PassphraseFunc func(confirm bool) (string, error)
+
+// SecretStore keeps the secret key of a profile outside of config.yml.
+type SecretStore interface {
+	Get(profile string) (string, error)
+	Set(profile, secret string) error
+	Delete(profile string) error
+}
+
+func ValidStore(store string) bool {
+	switch store {
+	case StorePlain, StoreKeyring, StoreFile:
+		return true
+	}
+	return false
+}
+
+// storeFor returns the backend for store. The plain store has no backend
+// because its secret lives in config.yml itself.
+func storeFor(store string) (SecretStore, error) {
+	switch store {
+	case StoreKeyring:
+		configFile, source, err := ResolvePath()
+		if err != nil {
+			return nil, err
+		}
+		legacy := source == PathSourceDefault
+		if dir, err := baseDir(); err == nil && configFile == filepath.Join(dir, configFileName) {
+			legacy = true
+		}
+		return keyringStore{configFile: configFile, legacy: legacy}, nil
+	case StoreFile:
+		configFile, err := Path()
+		if err != nil {
+			return nil, err
+		}
+		return fileStore{dir: filepath.Join(filepath.Dir(configFile), "secrets")}, nil
+	case "", StorePlain:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown secret store %q", store)
+	}
+}
+
+// LoadSecret returns the secret key of profile p named name, reading it from
+// the profile's secret store when it is not kept in plain text.
+func LoadSecret(name string, p Profile) (string, error) {
+	backend, err := storeFor(p.Store)
+	if err != nil || backend == nil {
+		return p.SecretKey, err
+	}
+
+	secret, err := backend.Get(name)
+	if err != nil {
+		return "", fmt.Errorf("unable to read secret for profile %q from %s store: %w", name, p.Store, err)
+	}
+	return secret, nil
+}
+
+// StoreSecret saves secret for the named profile in store and records the
+// store in f. If the profile previously used a different store, the secret is
+// removed from there. An empty store keeps the profile's current store.
+func StoreSecret(f *File, name, store, secret string) error {
+	p, _ := f.Profile(name)
+	if store == "" {
+		store = p.Store
+	}
+	if store == "" {
+		store = StorePlain
+	}
+
+	backend, err := storeFor(store)
+	if err != nil {
+		return err
+	}
+
+	if backend == nil {
+		p.SecretKey = secret
+	} else {
+		if err := backend.Set(name, secret); err != nil {
+			return fmt.Errorf("unable to save secret in %s store: %w", store, err)
+		}
+		p.SecretKey = ""
+	}
+
+	if p.Store != "" && p.Store != store {
+		if err := DeleteSecret(name, p); err != nil {
+			return err
+		}
+	}
+
+	p.Store = store
+	if store == StorePlain {
+		p.Store = ""
+	}
+	f.SetProfile(name, p)
+	return nil
+}
+
+// DeleteSecret removes the profile's secret from its secret store, if any.
+func DeleteSecret(name string, p Profile) error {
+	backend, err := storeFor(p.Store)
+	if err != nil || backend == nil {
+		return err
+	}
+	if err := backend.Delete(name); err != nil && !errors.Is(err, ErrSecretNotFound) {
+		return fmt.Errorf("unable to remove secret from %s store: %w", p.Store, err)
+	}
+	return nil
+}
+
+// keyringStore keeps secrets in the OS keyring: the Secret Service over D-Bus
+// on Linux, the Keychain on macOS and the Credential Manager on Windows.
+// Entries are keyed by profile and config file, so that profiles of the same
+// name in different config files keep separate secrets. Secrets of the
+// default config file saved before that, under the bare profile name, are
+// still read and are moved to the new entry when the secret is next set.
+type keyringStore struct {
+	configFile string
+	legacy     bool
+}
+
+func (s keyringStore) account(profile string) string {
+	return profile + "@" + s.configFile
+}
+
+func (s keyringStore) Get(profile string) (string, error) {
+	secret, err := keyring.Get(keyringService, s.account(profile))
+	if errors.Is(err, keyring.ErrNotFound) && s.legacy {
+		secret, err = keyring.Get(keyringService, profile)
+	}
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrSecretNotFound
+	}
+	return secret, err
+}
+
+func (s keyringStore) Set(profile, secret string) error {
+	if err := keyring.Set(keyringService, s.account(profile), secret); err != nil {
+		return err
+	}
+	if s.legacy {
+		keyring.Delete(keyringService, profile)
+	}
+	return nil
+}
+
+func (s keyringStore) Delete(profile string) error {
+	err := keyring.Delete(keyringService, s.account(profile))
+	if s.legacy {
+		if legacyErr := keyring.Delete(keyringService, profile); !errors.Is(legacyErr, keyring.ErrNotFound) {
+			err = legacyErr
+		}
+	}
+	if errors.Is(err, keyring.ErrNotFound) {
+		return ErrSecretNotFound
+	}
+	return err
+}
+
+// fileStore keeps each profile's secret in its own age file encrypted with a
+// passphrase, for headless machines without a keyring.
+type fileStore struct {
+	dir string
+}
+
+func (s fileStore) path(profile string) (string, error) {
+	if err := ValidateProfileName(profile); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, profile+".age"), nil
+}
+
+func (s fileStore) Get(profile string) (string, error) {
+	path, err := s.path(profile)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrSecretNotFound
+		}
+		return "", err
+	}
+
+	passphrase, err := passphrase(false)
+	if err != nil {
+		return "", err
+	}
+
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return "", err
+	}
+
+	r, err := age.Decrypt(bytes.NewReader(data), identity)
+	if err != nil {
+		return "", fmt.Errorf("unable to decrypt secret (wrong passphrase?): %w", err)
+	}
+
+	secret, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+func (s fileStore) Set(profile, secret string) error {
+	path, err := s.path(profile)
+	if err != nil {
+		return err
+	}
+	passphrase, err := passphrase(true)
+	if err != nil {
+		return err
+	}
+
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, recipient)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, secret); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0600)
+}
+
+func (s fileStore) Delete(profile string) error {
+	path, err := s.path(profile)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if os.IsNotExist(err) {
+		return ErrSecretNotFound
+	}
+	return err
+}
+
+func passphrase(confirm bool) (string, error) {
+	if env := os.Getenv(PassphraseEnv); env != "" {
+		return env, nil
+	}
+	if PassphraseFunc == nil {
+		return "", fmt.Errorf("a passphrase is required; set %s", PassphraseEnv)
+	}
+
+	pass, err := PassphraseFunc(confirm)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(pass) == "" {
+		return "", errors.New("passphrase cannot be empty")
+	}
+	return pass, nil
+}
@@ -1,16 +1,22 @@
-module ut
+module github.com/MhemedAbderrahmen/ut
 
 go 1.24.3
 
 require (
+	filippo.io/age v1.2.1
 	github.com/mitchellh/go-homedir v1.1.0
 	github.com/spf13/cobra v1.9.1
+	github.com/spf13/pflag v1.0.6
+	github.com/zalando/go-keyring v0.2.6
 	golang.org/x/term v0.32.0
 	gopkg.in/yaml.v2 v2.4.0
 )
 
 require (
+	al.essio.dev/pkg/shellescape v1.5.1 // indirect
+	github.com/danieljoos/wincred v1.2.2 // indirect
+	github.com/godbus/dbus/v5 v5.1.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/pflag v1.0.6 // indirect
+	golang.org/x/crypto v0.24.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
 )
@@ -1,6 +1,6 @@
 package main
 
-import "ut/cmd"
+import "github.com/MhemedAbderrahmen/ut/cmd"
 
 func main() {
 	cmd.Execute()
@@ -0,0 +1,160 @@
+// Package uploadthing is a client for the UploadThing REST API.
+//
+// A Client lists, uploads, downloads and deletes files on behalf of an app
+// identified by its secret API key:
+//
+//	client := uploadthing.NewClient(os.Getenv("UPLOADTHING_SECRET"))
+//	files, err := client.ListFiles(ctx, uploadthing.ListFilesRequest{Limit: 50})
+package uploadthing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultBaseURL is the UploadThing REST API.
+	DefaultBaseURL = "https://api.uploadthing.com"
+	// DefaultFileHost serves uploaded files by key.
+	DefaultFileHost = "https://utfs.io"
+	// UFSFileHost is the per-app file host scheme; {appId} is replaced with
+	// the client's AppID.
+	UFSFileHost = "https://{appId}.ufs.sh"
+)
+
+// Client talks to the UploadThing API. Its fields may be changed after
+// NewClient but not while requests are in flight.
+type Client struct {
+	// APIKey is the app's secret key, sent with every API call.
+	APIKey string
+	// BaseURL is the API endpoint, DefaultBaseURL unless overridden.
+	BaseURL string
+	// FileHost is the origin files are downloaded from. It may contain
+	// {appId}, see UFSFileHost.
+	FileHost string
+	// AppID fills in {appId} in FileHost.
+	AppID string
+	// HTTPClient performs API calls.
+	HTTPClient *http.Client
+	// TransferClient uploads and downloads file contents, which may take far
+	// longer than an API call. It should not have a short timeout.
+	TransferClient *http.Client
+
+	// PartConcurrency is the number of parts of a multipart upload sent in
+	// parallel.
+	PartConcurrency int
+	// PartRetries is how often a failed part is retried.
+	PartRetries int
+
+	// Retries is how often a request failing with a transient error is
+	// retried. See do for which failures are retried.
+	Retries int
+	// RetryMaxWait caps the wait before a retry. A Retry-After longer than
+	// this is not waited for.
+	RetryMaxWait time.Duration
+
+	// Logf, if set, receives progress messages such as multipart part
+	// completions and retries.
+	Logf func(format string, args ...any)
+}
+
+// NewClient returns a client for the app with the given secret key using the
+// default endpoints.
+func NewClient(apiKey string) *Client {
+	return &Client{
+		APIKey:          apiKey,
+		BaseURL:         DefaultBaseURL,
+		FileHost:        DefaultFileHost,
+		HTTPClient:      &http.Client{Timeout: 30 * time.Second},
+		TransferClient:  &http.Client{},
+		PartConcurrency: 4,
+		PartRetries:     3,
+		Retries:         DefaultRetries,
+		RetryMaxWait:    DefaultRetryMaxWait,
+	}
+}
+
+// idempotentPaths are the API calls that are safe to repeat after a network
+// error or gateway failure.
+var idempotentPaths = map[string]bool{
+	"/v6/listFiles":         true,
+	"/v6/renameFiles":       true,
+	"/v6/updateACL":         true,
+	"/v6/requestFileAccess": true,
+	"/v6/getUsageInfo":      true,
+	"/v6/completeMultipart": true,
+	"/v6/failureCallback":   true,
+}
+
+func (c *Client) logf(format string, args ...any) {
+	if c.Logf != nil {
+		c.Logf(format, args...)
+	}
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) transferClient() *http.Client {
+	if c.TransferClient != nil {
+		return c.TransferClient
+	}
+	return http.DefaultClient
+}
+
+// post sends payload as JSON to the API path and decodes the response into
+// out, which may be nil.
+func (c *Client) post(ctx context.Context, path string, payload any, out any) error {
+	jsonBody, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+path, bytes.NewReader(jsonBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Uploadthing-Api-Key", c.APIKey)
+	if idempotentPaths[path] {
+		markIdempotent(req)
+	}
+
+	resp, err := c.do(c.httpClient(), req)
+	if err != nil {
+		return fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return &APIError{StatusCode: resp.StatusCode, Path: path, Body: string(body)}
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(body, out); err != nil {
+			return fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package uploadthing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client whose API and file host are srv, retrying
+// without noticeable delays.
+func newTestClient(srv *httptest.Server) *Client {
+	c := NewClient("sk_test_key")
+	c.BaseURL = srv.URL
+	c.FileHost = srv.URL
+	c.HTTPClient = srv.Client()
+	c.TransferClient = srv.Client()
+	c.RetryMaxWait = time.Millisecond
+	return c
+}
+
+func TestPostSendsJSONWithAPIKey(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v6/listFiles" {
+			t.Errorf("request = %s %s, want POST /v6/listFiles", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("X-Uploadthing-Api-Key"); got != "sk_test_key" {
+			t.Errorf("X-Uploadthing-Api-Key = %q, want sk_test_key", got)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", got)
+		}
+		if _, ok := r.Header["Idempotency-Key"]; ok {
+			t.Error("Idempotency-Key marker was sent over the wire")
+		}
+
+		var req ListFilesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		if req.Limit != 10 || req.Offset != 20 {
+			t.Errorf("request = %+v, want limit 10 offset 20", req)
+		}
+
+		io.WriteString(w, `{"hasMore":true,"files":[{"id":"1","name":"a.txt","size":3,"key":"k-a","customId":"c-a","uploadedAt":1700000000000}]}`)
+	}))
+	defer srv.Close()
+
+	resp, err := newTestClient(srv).ListFiles(context.Background(), ListFilesRequest{Limit: 10, Offset: 20})
+	if err != nil {
+		t.Fatalf("ListFiles: %v", err)
+	}
+	want := FileInfo{ID: "1", Name: "a.txt", Size: 3, FileKey: "k-a", CustomID: "c-a", UploadedAt: 1700000000000}
+	if !resp.HasMore || len(resp.Files) != 1 || resp.Files[0] != want {
+		t.Errorf("ListFiles = %+v, want hasMore and [%+v]", resp, want)
+	}
+}
+
+func TestPostMapsErrorStatuses(t *testing.T) {
+	tests := []struct {
+		status  int
+		body    string
+		target  error
+		message string
+	}{
+		{http.StatusUnauthorized, `{"error":"bad key"}`, ErrUnauthorized, "request unauthorized: invalid API key"},
+		{http.StatusForbidden, "", ErrUnauthorized, "request unauthorized: invalid API key"},
+		{http.StatusNotFound, `{"error":"not found"}`, ErrNotFound, `API request failed: status 404, response: {"error":"not found"}`},
+		{http.StatusBadRequest, `{"error":"invalid"}`, nil, `API request failed: status 400, response: {"error":"invalid"}`},
+		{http.StatusInternalServerError, "", nil, "HTTP error: 500 Internal Server Error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			}))
+			defer srv.Close()
+
+			client := newTestClient(srv)
+			client.Retries = 0
+			_, err := client.DeleteFiles(context.Background(), DeleteFilesRequest{FileKeys: []string{"k"}})
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("error = %v, want *APIError", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Path != "/v6/deleteFiles" || apiErr.Body != tt.body {
+				t.Errorf("APIError = %+v", apiErr)
+			}
+			if err.Error() != tt.message {
+				t.Errorf("message = %q, want %q", err.Error(), tt.message)
+			}
+			for _, sentinel := range []error{ErrUnauthorized, ErrNotFound} {
+				if got, want := errors.Is(err, sentinel), sentinel == tt.target; got != want {
+					t.Errorf("errors.Is(err, %v) = %v, want %v", sentinel, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFileURL(t *testing.T) {
+	tests := []struct {
+		host, appID string
+		want        string
+		wantErr     bool
+	}{
+		{"", "", DefaultFileHost + "/f/key", false},
+		{"https://files.example.com/", "", "https://files.example.com/f/key", false},
+		{UFSFileHost, "abc123", "https://abc123.ufs.sh/f/key", false},
+		{UFSFileHost, "", "", true},
+	}
+
+	for _, tt := range tests {
+		c := &Client{FileHost: tt.host, AppID: tt.appID}
+		got, err := c.FileURL("key")
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("FileURL with host %q and app %q = %q, %v; want %q (error %v)", tt.host, tt.appID, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPostDoesNotSendAfterCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request sent with a canceled context")
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := newTestClient(srv).GetUsageInfo(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "API request failed") {
+		t.Errorf("error %q is not wrapped", err)
+	}
+}
@@ -0,0 +1,109 @@
+package uploadthing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// FileURL returns the public URL of the file with the given key. It fails if
+// FileHost contains {appId} but AppID is not set.
+func (c *Client) FileURL(fileKey string) (string, error) {
+	host := c.FileHost
+	if host == "" {
+		host = DefaultFileHost
+	}
+	if strings.Contains(host, "{appId}") {
+		if c.AppID == "" {
+			return "", fmt.Errorf("file host %s needs an app ID", host)
+		}
+		host = strings.ReplaceAll(host, "{appId}", c.AppID)
+	}
+	return strings.TrimSuffix(host, "/") + "/f/" + fileKey, nil
+}
+
+// FileHeaders are the HTTP headers a file is served with.
+type FileHeaders struct {
+	ContentType        string
+	ContentLength      int64
+	ContentDisposition string
+	ETag               string
+	LastModified       time.Time
+}
+
+// Head requests the headers of fileURL without downloading the file. A
+// private file requested by its public URL fails with ErrUnauthorized.
+func (c *Client) Head(ctx context.Context, fileURL string) (*FileHeaders, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, fileURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(c.httpClient(), req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &APIError{StatusCode: resp.StatusCode, Path: fileURL}
+	}
+
+	headers := &FileHeaders{
+		ContentType:        resp.Header.Get("Content-Type"),
+		ContentLength:      resp.ContentLength,
+		ContentDisposition: resp.Header.Get("Content-Disposition"),
+		ETag:               resp.Header.Get("ETag"),
+	}
+	if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		headers.LastModified = lastModified
+	}
+	return headers, nil
+}
+
+// Download starts downloading fileURL and returns the body along with its
+// length, or -1 if the length is unknown. The caller must close the body.
+func (c *Client) Download(ctx context.Context, fileURL string) (io.ReadCloser, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(c.transferClient(), req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("HTTP request failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, 0, &APIError{StatusCode: resp.StatusCode, Path: fileURL}
+	}
+
+	return resp.Body, resp.ContentLength, nil
+}
+
+// DownloadRange requests fileURL from byte offset on. When ifRange is set it
+// is sent as If-Range, so the server returns the whole file with 200 instead
+// of 206 if the file no longer matches. The response is returned as is for the
+// caller to inspect; the caller must close its body.
+func (c *Client) DownloadRange(ctx context.Context, fileURL string, offset int64, ifRange string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if ifRange != "" {
+			req.Header.Set("If-Range", ifRange)
+		}
+	}
+
+	resp, err := c.do(c.transferClient(), req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,44 @@
+package uploadthing
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrUnauthorized is reported when the API rejects the secret key.
+	ErrUnauthorized = errors.New("invalid API key")
+	// ErrNotFound is reported when a file does not exist.
+	ErrNotFound = errors.New("file not found")
+)
+
+// APIError is a non-success response from the UploadThing API or file
+// storage. It matches ErrUnauthorized for 401 and 403 responses and
+// ErrNotFound for 404 responses with errors.Is.
+type APIError struct {
+	StatusCode int
+	// Path is the API path, or the URL for file storage requests.
+	Path string
+	Body string
+}
+
+func (e *APIError) Error() string {
+	if e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden {
+		return "request unauthorized: " + ErrUnauthorized.Error()
+	}
+	if e.Body == "" {
+		return fmt.Sprintf("HTTP error: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("API request failed: status %d, response: %s", e.StatusCode, e.Body)
+}
+
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrUnauthorized
+	case http.StatusNotFound:
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package uploadthing
+
+import (
+	"context"
+	"time"
+)
+
+type ListFilesRequest struct {
+	Limit  int `json:"limit,omitempty"`
+	Offset int `json:"offset,omitempty"`
+}
+
+type FilesResponse struct {
+	HasMore bool       `json:"hasMore"`
+	Files   []FileInfo `json:"files"`
+}
+
+type FileInfo struct {
+	ID         string `json:"id" yaml:"id"`
+	Name       string `json:"name" yaml:"name"`
+	Size       int64  `json:"size" yaml:"size"`
+	FileKey    string `json:"key" yaml:"key"`
+	CustomID   string `json:"customId,omitempty" yaml:"customId,omitempty"`
+	Status     string `json:"status,omitempty" yaml:"status,omitempty"`
+	UploadedAt int64  `json:"uploadedAt" yaml:"uploadedAt"`
+}
+
+// UploadedTime returns UploadedAt as a time. The API reports milliseconds, but
+// values small enough to be seconds are treated as seconds.
+func (f FileInfo) UploadedTime() time.Time {
+	if f.UploadedAt > 1e12 {
+		return time.UnixMilli(f.UploadedAt)
+	}
+	return time.Unix(f.UploadedAt, 0)
+}
+
+type DeleteFilesRequest struct {
+	FileKeys  []string `json:"fileKeys,omitempty"`
+	CustomIDs []string `json:"customIds,omitempty"`
+}
+
+type DeleteFilesResponse struct {
+	Success      bool `json:"success"`
+	DeletedCount int  `json:"deletedCount"`
+}
+
+// RenameUpdate renames the file identified by FileKey or CustomID. The API
+// cannot change a file's custom ID; CustomID only selects the file.
+type RenameUpdate struct {
+	FileKey  string `json:"fileKey,omitempty"`
+	CustomID string `json:"customId,omitempty"`
+	NewName  string `json:"newName"`
+}
+
+type RenameFilesRequest struct {
+	Updates []RenameUpdate `json:"updates"`
+}
+
+type RenameFilesResponse struct {
+	Success bool `json:"success"`
+}
+
+// ACLUpdate sets the ACL of the file identified by FileKey or CustomID.
+type ACLUpdate struct {
+	FileKey  string `json:"fileKey,omitempty"`
+	CustomID string `json:"customId,omitempty"`
+	ACL      string `json:"acl"`
+}
+
+type UpdateACLRequest struct {
+	Updates []ACLUpdate `json:"updates"`
+}
+
+type UpdateACLResponse struct {
+	Success bool `json:"success"`
+}
+
+type FileAccessRequest struct {
+	FileKey string `json:"fileKey"`
+}
+
+type FileAccessResponse struct {
+	URL string `json:"url"`
+}
+
+type UsageInfo struct {
+	TotalBytes    int64 `json:"totalBytes"`
+	AppTotalBytes int64 `json:"appTotalBytes"`
+	FilesUploaded int   `json:"filesUploaded"`
+	LimitBytes    int64 `json:"limitBytes"`
+}
+
+// ListFiles returns one page of the app's files.
+func (c *Client) ListFiles(ctx context.Context, req ListFilesRequest) (*FilesResponse, error) {
+	var resp FilesResponse
+	if err := c.post(ctx, "/v6/listFiles", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteFiles deletes files by key or custom ID.
+func (c *Client) DeleteFiles(ctx context.Context, req DeleteFilesRequest) (*DeleteFilesResponse, error) {
+	var resp DeleteFilesResponse
+	if err := c.post(ctx, "/v6/deleteFiles", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RenameFiles changes the names of files.
+func (c *Client) RenameFiles(ctx context.Context, req RenameFilesRequest) (*RenameFilesResponse, error) {
+	var resp RenameFilesResponse
+	if err := c.post(ctx, "/v6/renameFiles", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UpdateACL changes whether files are public or private.
+func (c *Client) UpdateACL(ctx context.Context, req UpdateACLRequest) (*UpdateACLResponse, error) {
+	var resp UpdateACLResponse
+	if err := c.post(ctx, "/v6/updateACL", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RequestFileAccess returns a signed URL for downloading a private file.
+func (c *Client) RequestFileAccess(ctx context.Context, fileKey string) (string, error) {
+	var resp FileAccessResponse
+	if err := c.post(ctx, "/v6/requestFileAccess", FileAccessRequest{FileKey: fileKey}, &resp); err != nil {
+		return "", err
+	}
+	return resp.URL, nil
+}
+
+// GetUsageInfo reports the app's storage usage. It has no side effects, so it
+// is also a cheap way to check that a secret key is valid.
+func (c *Client) GetUsageInfo(ctx context.Context) (*UsageInfo, error) {
+	var resp UsageInfo
+	if err := c.post(ctx, "/v6/getUsageInfo", struct{}{}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// FileIterator walks the listFiles endpoint page by page.
+type FileIterator struct {
+	client   *Client
+	pageSize int
+	offset   int
+	hasMore  bool
+}
+
+// Files returns an iterator over the app's files starting at offset, fetching
+// pageSize files per request. A pageSize of 0 uses the server default.
+func (c *Client) Files(pageSize, offset int) *FileIterator {
+	return &FileIterator{
+		client:   c,
+		pageSize: pageSize,
+		offset:   offset,
+		hasMore:  true,
+	}
+}
+
+func (it *FileIterator) HasNext() bool {
+	return it.hasMore
+}
+
+// Offset is the offset of the next page.
+func (it *FileIterator) Offset() int {
+	return it.offset
+}
+
+// Next fetches the next page of files.
+func (it *FileIterator) Next(ctx context.Context) ([]FileInfo, error) {
+	if !it.hasMore {
+		return nil, nil
+	}
+
+	resp, err := it.client.ListFiles(ctx, ListFilesRequest{Limit: it.pageSize, Offset: it.offset})
+	if err != nil {
+		return nil, err
+	}
+
+	it.offset += len(resp.Files)
+	it.hasMore = resp.HasMore && len(resp.Files) > 0
+
+	return resp.Files, nil
+}
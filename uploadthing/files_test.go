@@ -0,0 +1,117 @@
+package uploadthing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// listServer serves total files from the listFiles endpoint, honoring limit
+// and offset, and records the requests it received.
+func listServer(t *testing.T, total int, requests *[]ListFilesRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ListFilesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		*requests = append(*requests, req)
+
+		resp := FilesResponse{Files: []FileInfo{}}
+		for i := req.Offset; i < total && i < req.Offset+req.Limit; i++ {
+			resp.Files = append(resp.Files, FileInfo{FileKey: fmt.Sprintf("key-%d", i)})
+		}
+		resp.HasMore = req.Offset+len(resp.Files) < total
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestFileIteratorWalksAllPages(t *testing.T) {
+	var requests []ListFilesRequest
+	srv := listServer(t, 5, &requests)
+	defer srv.Close()
+
+	it := newTestClient(srv).Files(2, 0)
+	var keys []string
+	for it.HasNext() {
+		files, err := it.Next(context.Background())
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		for _, f := range files {
+			keys = append(keys, f.FileKey)
+		}
+	}
+
+	wantKeys := []string{"key-0", "key-1", "key-2", "key-3", "key-4"}
+	if !reflect.DeepEqual(keys, wantKeys) {
+		t.Errorf("keys = %v, want %v", keys, wantKeys)
+	}
+	wantRequests := []ListFilesRequest{{Limit: 2, Offset: 0}, {Limit: 2, Offset: 2}, {Limit: 2, Offset: 4}}
+	if !reflect.DeepEqual(requests, wantRequests) {
+		t.Errorf("requests = %+v, want %+v", requests, wantRequests)
+	}
+	if it.Offset() != 5 {
+		t.Errorf("Offset = %d, want 5", it.Offset())
+	}
+
+	files, err := it.Next(context.Background())
+	if files != nil || err != nil {
+		t.Errorf("Next after the last page = %v, %v; want nil, nil", files, err)
+	}
+}
+
+func TestFileIteratorStartsAtOffset(t *testing.T) {
+	var requests []ListFilesRequest
+	srv := listServer(t, 5, &requests)
+	defer srv.Close()
+
+	it := newTestClient(srv).Files(10, 3)
+	files, err := it.Next(context.Background())
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if len(files) != 2 || files[0].FileKey != "key-3" {
+		t.Errorf("files = %+v, want key-3 and key-4", files)
+	}
+	if it.HasNext() {
+		t.Error("HasNext = true after the last page")
+	}
+}
+
+func TestFileIteratorStopsOnEmptyPage(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		json.NewEncoder(w).Encode(FilesResponse{HasMore: true, Files: []FileInfo{}})
+	}))
+	defer srv.Close()
+
+	it := newTestClient(srv).Files(2, 0)
+	for it.HasNext() && calls < 3 {
+		if _, err := it.Next(context.Background()); err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("listFiles called %d times, want 1 when the server reports more files but sends none", calls)
+	}
+}
+
+func TestFileIteratorReturnsErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	it := newTestClient(srv).Files(2, 0)
+	if _, err := it.Next(context.Background()); err == nil {
+		t.Fatal("Next succeeded on a 401 response")
+	}
+	if !it.HasNext() || it.Offset() != 0 {
+		t.Errorf("failed page moved the iterator: HasNext %v, Offset %d", it.HasNext(), it.Offset())
+	}
+}
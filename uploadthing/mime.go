@@ -0,0 +1,61 @@
+package uploadthing
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// fallbackTypes covers common web formats that are missing from minimal
+// systems without a mime.types file. The system database takes precedence.
+var fallbackTypes = map[string]string{
+	".avif":  "image/avif",
+	".csv":   "text/csv",
+	".gz":    "application/gzip",
+	".heic":  "image/heic",
+	".ico":   "image/vnd.microsoft.icon",
+	".md":    "text/markdown",
+	".mov":   "video/quicktime",
+	".mp3":   "audio/mpeg",
+	".mp4":   "video/mp4",
+	".ogg":   "audio/ogg",
+	".otf":   "font/otf",
+	".tar":   "application/x-tar",
+	".ttf":   "font/ttf",
+	".txt":   "text/plain",
+	".wav":   "audio/wav",
+	".webm":  "video/webm",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+	".yaml":  "application/yaml",
+	".yml":   "application/yaml",
+	".zip":   "application/zip",
+}
+
+// DetectContentType guesses the MIME type of a file from its name and, if the
+// extension is unknown, from its first 512 bytes. Extensions are looked up in
+// the system MIME database (/etc/mime.types and friends on Unix, the registry
+// on Windows) and then in a built-in table of common web formats. file may be
+// nil to skip sniffing.
+func DetectContentType(name string, file io.ReaderAt) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext != "" {
+		if contentType := mime.TypeByExtension(ext); contentType != "" {
+			return contentType
+		}
+		if contentType, ok := fallbackTypes[ext]; ok {
+			return contentType
+		}
+	}
+
+	if file != nil {
+		buf := make([]byte, 512)
+		n, err := file.ReadAt(buf, 0)
+		if n > 0 && (err == nil || err == io.EOF) {
+			return http.DetectContentType(buf[:n])
+		}
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,48 @@
+package uploadthing
+
+import (
+	"mime"
+	"strings"
+	"testing"
+)
+
+func TestDetectContentType(t *testing.T) {
+	// The system MIME database differs between machines and wins over the
+	// built-in table, so expect whatever it has for an extension, if anything.
+	systemOr := func(ext, fallback string) string {
+		if contentType := mime.TypeByExtension(ext); contentType != "" {
+			return contentType
+		}
+		return fallback
+	}
+
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 16)
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{"webp", "photo.webp", "", "image/webp"},
+		{"svg", "logo.svg", "", "image/svg+xml"},
+		{"upper-case extension", "PHOTO.WEBP", "", "image/webp"},
+		{"woff2 from the system or built-in table", "font.woff2", "", systemOr(".woff2", "font/woff2")},
+		{"heic from the system or built-in table", "IMG_0001.heic", "", systemOr(".heic", "image/heic")},
+		{"extension wins over content", "notes.svg", png, "image/svg+xml"},
+		{"unknown extension is sniffed", "image.unknownext", png, "image/png"},
+		{"no extension is sniffed", "README", "hello, world\n", "text/plain; charset=utf-8"},
+		{"empty file", "data.unknownext", "", "application/octet-stream"},
+	}
+
+	for _, tt := range tests {
+		got := DetectContentType(tt.file, strings.NewReader(tt.content))
+		if got != tt.want {
+			t.Errorf("%s: DetectContentType(%q) = %q, want %q", tt.name, tt.file, got, tt.want)
+		}
+	}
+
+	if got := DetectContentType("image.unknownext", nil); got != "application/octet-stream" {
+		t.Errorf("DetectContentType without a file = %q, want application/octet-stream", got)
+	}
+}
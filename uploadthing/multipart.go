@@ -0,0 +1,180 @@
+package uploadthing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type CompletedPart struct {
+	Tag        string `json:"tag"`
+	PartNumber int    `json:"partNumber"`
+}
+
+type CompleteMultipartRequest struct {
+	FileKey  string          `json:"fileKey"`
+	UploadID string          `json:"uploadId"`
+	Etags    []CompletedPart `json:"etags"`
+}
+
+type MultipartFailureRequest struct {
+	FileKey  string `json:"fileKey"`
+	UploadID string `json:"uploadId"`
+}
+
+// CompleteMultipart finalizes a multipart upload once every part is stored.
+func (c *Client) CompleteMultipart(ctx context.Context, req CompleteMultipartRequest) error {
+	return c.post(ctx, "/v6/completeMultipart", req, nil)
+}
+
+// FailMultipart reports an aborted multipart upload so that UploadThing can
+// clean up the parts already stored.
+func (c *Client) FailMultipart(ctx context.Context, req MultipartFailureRequest) error {
+	return c.post(ctx, "/v6/failureCallback", req, nil)
+}
+
+// uploadMultipart uploads file in the chunks described by upload, using up to
+// PartConcurrency parallel requests and retrying each part on failure. The
+// upload is finalized with completeMultipart, or reported as failed so that
+// UploadThing can clean up the parts already stored.
+func (c *Client) uploadMultipart(ctx context.Context, upload PresignedUpload, file io.ReaderAt, fileSize int64) error {
+	if upload.ChunkSize <= 0 {
+		return fmt.Errorf("invalid chunk size %d in multipart upload", upload.ChunkSize)
+	}
+
+	partCount := len(upload.URLs)
+	expected := int((fileSize + upload.ChunkSize - 1) / upload.ChunkSize)
+	if partCount != max(expected, 1) {
+		return fmt.Errorf("received %d part URLs for a %d byte file with %d byte chunks",
+			partCount, fileSize, upload.ChunkSize)
+	}
+
+	workers := max(c.PartConcurrency, 1)
+
+	c.logf("Uploading %d parts of up to %d bytes (%d at a time)...\n", partCount, upload.ChunkSize, workers)
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		parts    []CompletedPart
+		firstErr error
+		done     int
+	)
+
+	jobs := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				offset := int64(i) * upload.ChunkSize
+				length := min(upload.ChunkSize, fileSize-offset)
+
+				tag, err := c.uploadPartWithRetry(ctx, upload.URLs[i], file, offset, length, i+1)
+
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+				} else {
+					parts = append(parts, CompletedPart{Tag: tag, PartNumber: i + 1})
+					done++
+					c.logf("Uploaded part %d/%d\n", done, partCount)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i := 0; i < partCount; i++ {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		failureReq := MultipartFailureRequest{FileKey: upload.Key, UploadID: upload.UploadID}
+		if err := c.FailMultipart(context.WithoutCancel(ctx), failureReq); err != nil {
+			c.logf("Warning: failed to report aborted multipart upload: %v\n", err)
+		}
+		return firstErr
+	}
+
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].PartNumber < parts[j].PartNumber
+	})
+
+	completeReq := CompleteMultipartRequest{
+		FileKey:  upload.Key,
+		UploadID: upload.UploadID,
+		Etags:    parts,
+	}
+	if err := c.CompleteMultipart(ctx, completeReq); err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) uploadPartWithRetry(ctx context.Context, partURL string, file io.ReaderAt, offset, length int64, partNumber int) (string, error) {
+	retries := max(c.PartRetries, 0)
+
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			wait := time.Duration(1<<(attempt-1)) * 500 * time.Millisecond
+			c.logf("Retrying part %d in %s (attempt %d/%d): %v\n", partNumber, wait, attempt+1, retries+1, lastErr)
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+
+		tag, err := c.UploadPart(ctx, partURL, io.NewSectionReader(file, offset, length), length)
+		if err == nil {
+			return tag, nil
+		}
+		lastErr = err
+	}
+	return "", fmt.Errorf("part %d failed after %d attempts: %w", partNumber, retries+1, lastErr)
+}
+
+// UploadPart PUTs one chunk of a multipart upload and returns its ETag.
+func (c *Client) UploadPart(ctx context.Context, partURL string, body io.Reader, length int64) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, partURL, body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create part request: %w", err)
+	}
+	req.ContentLength = length
+
+	resp, err := c.do(c.transferClient(), req)
+	if err != nil {
+		return "", fmt.Errorf("part request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("status %d, response: %s", resp.StatusCode, string(respBody))
+	}
+	io.Copy(io.Discard, resp.Body)
+
+	tag := strings.Trim(resp.Header.Get("ETag"), `"`)
+	if tag == "" {
+		return "", fmt.Errorf("storage response is missing an ETag")
+	}
+	return tag, nil
+}
@@ -0,0 +1,148 @@
+package uploadthing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// multipartServer accepts part PUTs on /part/<n> and records the parts and
+// the completeMultipart and failureCallback requests. failPart, if set, is
+// answered with 400.
+type multipartServer struct {
+	*httptest.Server
+	failPart int
+
+	mu       sync.Mutex
+	parts    map[int]string
+	complete *CompleteMultipartRequest
+	failure  *MultipartFailureRequest
+}
+
+func newMultipartServer(t *testing.T, failPart int) *multipartServer {
+	s := &multipartServer{failPart: failPart, parts: map[int]string{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/part/"):
+			n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/part/"))
+			if n == s.failPart {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			// Finish later parts first so completion order differs from part order.
+			time.Sleep(time.Duration(10-n) * time.Millisecond)
+			data, _ := io.ReadAll(r.Body)
+			s.mu.Lock()
+			s.parts[n] = string(data)
+			s.mu.Unlock()
+			w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, n))
+		case r.URL.Path == "/v6/completeMultipart":
+			var req CompleteMultipartRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			s.mu.Lock()
+			s.complete = &req
+			s.mu.Unlock()
+		case r.URL.Path == "/v6/failureCallback":
+			var req MultipartFailureRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			s.mu.Lock()
+			s.failure = &req
+			s.mu.Unlock()
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return s
+}
+
+func (s *multipartServer) upload(content string, chunkSize int64) PresignedUpload {
+	count := (int64(len(content)) + chunkSize - 1) / chunkSize
+	upload := PresignedUpload{Key: "file-key", UploadID: "upload-1", ChunkSize: chunkSize}
+	for i := int64(1); i <= count; i++ {
+		upload.URLs = append(upload.URLs, fmt.Sprintf("%s/part/%d", s.URL, i))
+	}
+	return upload
+}
+
+func TestUploadMultipartCompletesPartsInOrder(t *testing.T) {
+	srv := newMultipartServer(t, 0)
+	defer srv.Close()
+
+	content := "aaaabbbbccccddddeeeeff"
+	client := newTestClient(srv.Server)
+	client.PartConcurrency = 6
+
+	upload := srv.upload(content, 4)
+	if err := client.UploadPresigned(context.Background(), upload, "f.bin", strings.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("UploadPresigned: %v", err)
+	}
+
+	wantParts := map[int]string{1: "aaaa", 2: "bbbb", 3: "cccc", 4: "dddd", 5: "eeee", 6: "ff"}
+	for n, want := range wantParts {
+		if srv.parts[n] != want {
+			t.Errorf("part %d = %q, want %q", n, srv.parts[n], want)
+		}
+	}
+
+	if srv.complete == nil {
+		t.Fatal("completeMultipart was not called")
+	}
+	if srv.complete.FileKey != "file-key" || srv.complete.UploadID != "upload-1" {
+		t.Errorf("completeMultipart = %+v", srv.complete)
+	}
+	if len(srv.complete.Etags) != len(wantParts) {
+		t.Fatalf("completeMultipart has %d parts, want %d", len(srv.complete.Etags), len(wantParts))
+	}
+	for i, part := range srv.complete.Etags {
+		want := CompletedPart{Tag: fmt.Sprintf("etag-%d", i+1), PartNumber: i + 1}
+		if part != want {
+			t.Errorf("part %d = %+v, want %+v", i, part, want)
+		}
+	}
+	if srv.failure != nil {
+		t.Error("failureCallback called for a successful upload")
+	}
+}
+
+func TestUploadMultipartReportsFailure(t *testing.T) {
+	srv := newMultipartServer(t, 2)
+	defer srv.Close()
+
+	content := "aaaabbbbcccc"
+	client := newTestClient(srv.Server)
+	client.PartConcurrency = 1
+	client.PartRetries = 1
+
+	upload := srv.upload(content, 4)
+	err := client.UploadPresigned(context.Background(), upload, "f.bin", strings.NewReader(content), int64(len(content)))
+	if err == nil || !strings.Contains(err.Error(), "part 2 failed after 2 attempts") {
+		t.Fatalf("error = %v, want part 2 to fail after 2 attempts", err)
+	}
+
+	if srv.complete != nil {
+		t.Error("completeMultipart called for a failed upload")
+	}
+	want := MultipartFailureRequest{FileKey: "file-key", UploadID: "upload-1"}
+	if srv.failure == nil || *srv.failure != want {
+		t.Errorf("failureCallback = %+v, want %+v", srv.failure, want)
+	}
+}
+
+func TestUploadMultipartRejectsMismatchedParts(t *testing.T) {
+	client := NewClient("sk_test_key")
+	upload := PresignedUpload{Key: "k", UploadID: "u", ChunkSize: 4, URLs: []string{"http://invalid/1"}}
+
+	err := client.UploadPresigned(context.Background(), upload, "f.bin", strings.NewReader("aaaabbbb"), 8)
+	if err == nil || !strings.Contains(err.Error(), "received 1 part URLs") {
+		t.Errorf("error = %v, want a part count mismatch", err)
+	}
+}
@@ -0,0 +1,160 @@
+package uploadthing
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultRetries is how often a transient failure is retried.
+	DefaultRetries = 3
+	// DefaultRetryMaxWait caps the wait before a single retry.
+	DefaultRetryMaxWait = 30 * time.Second
+
+	retryBaseDelay = 500 * time.Millisecond
+)
+
+// markIdempotent flags a POST as safe to repeat. A nil Idempotency-Key header
+// is the net/http convention for this and is not sent over the wire.
+func markIdempotent(req *http.Request) {
+	req.Header["Idempotency-Key"] = nil
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	_, ok := req.Header["Idempotency-Key"]
+	return ok
+}
+
+// isPermanent reports whether a failed request would fail the same way again:
+// an unknown host, a certificate that does not verify, or a URL that cannot
+// be requested.
+func isPermanent(req *http.Request, err error) bool {
+	if req.URL == nil || req.URL.Host == "" || (req.URL.Scheme != "http" && req.URL.Scheme != "https") {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return true
+	}
+
+	var (
+		verifyErr    *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	return errors.As(err, &verifyErr) || errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
+
+// do sends req with hc and retries transient failures with jittered
+// exponential backoff. 429 and 503 responses mean the request was not
+// processed and are retried for every request; other 5xx gateway errors and
+// network errors are only retried for idempotent requests, and errors that
+// isPermanent reports are never retried. A Retry-After
+// header is honored unless it asks for a longer wait than RetryMaxWait, in
+// which case the response is returned as is. Requests whose body cannot be
+// replayed are sent once.
+func (c *Client) do(hc *http.Client, req *http.Request) (*http.Response, error) {
+	idempotent := isIdempotent(req)
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := hc.Do(req)
+		if attempt >= c.Retries || !replayable || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		wait := c.backoff(attempt)
+		var reason string
+		switch {
+		case err != nil:
+			if !idempotent || isPermanent(req, err) {
+				return nil, err
+			}
+			reason = err.Error()
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable,
+			idempotent && (resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusGatewayTimeout):
+			if after, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				if after > c.maxWait() {
+					return resp, nil
+				}
+				wait = after
+			}
+			reason = resp.Status
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		default:
+			return resp, nil
+		}
+
+		c.logf("Retrying %s %s in %s (attempt %d/%d): %s\n", req.Method, req.URL.Path, wait.Round(time.Millisecond), attempt+2, c.Retries+1, reason)
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) maxWait() time.Duration {
+	if c.RetryMaxWait > 0 {
+		return c.RetryMaxWait
+	}
+	return DefaultRetryMaxWait
+}
+
+// backoff returns a random wait of up to retryBaseDelay * 2^attempt, capped
+// at the maximum wait ("full jitter").
+func (c *Client) backoff(attempt int) time.Duration {
+	limit := c.maxWait()
+	if attempt < 30 {
+		limit = min(limit, retryBaseDelay<<attempt)
+	}
+	return time.Duration(rand.Int64N(int64(limit)) + 1)
+}
+
+// parseRetryAfter parses a Retry-After value given in seconds or as an HTTP
+// date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
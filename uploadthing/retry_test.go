@@ -0,0 +1,256 @@
+package uploadthing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"0", 0, true},
+		{"5", 5 * time.Second, true},
+		{"120", 2 * time.Minute, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{"Wed, 01 May 2024 12:00:30 GMT", 30 * time.Second, true},
+		{"Wednesday, 01-May-24 12:01:00 GMT", time.Minute, true},
+		{"Wed, 01 May 2024 11:59:00 GMT", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestBackoffStaysWithinLimits(t *testing.T) {
+	c := &Client{RetryMaxWait: 3 * time.Second}
+	for attempt := 0; attempt < 40; attempt++ {
+		limit := min(c.RetryMaxWait, retryBaseDelay<<min(attempt, 30))
+		for i := 0; i < 100; i++ {
+			if wait := c.backoff(attempt); wait <= 0 || wait > limit {
+				t.Fatalf("backoff(%d) = %v, want in (0, %v]", attempt, wait, limit)
+			}
+		}
+	}
+}
+
+func TestDoRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		idempotent bool
+		status     int
+		retryAfter string
+		wantCalls  int
+		wantStatus int
+	}{
+		{"429 on POST", http.MethodPost, false, http.StatusTooManyRequests, "", 3, http.StatusTooManyRequests},
+		{"503 on POST", http.MethodPost, false, http.StatusServiceUnavailable, "", 3, http.StatusServiceUnavailable},
+		{"502 on POST", http.MethodPost, false, http.StatusBadGateway, "", 1, http.StatusBadGateway},
+		{"504 on POST", http.MethodPost, false, http.StatusGatewayTimeout, "", 1, http.StatusGatewayTimeout},
+		{"502 on idempotent POST", http.MethodPost, true, http.StatusBadGateway, "", 3, http.StatusBadGateway},
+		{"504 on GET", http.MethodGet, false, http.StatusGatewayTimeout, "", 3, http.StatusGatewayTimeout},
+		{"502 on PUT", http.MethodPut, false, http.StatusBadGateway, "", 3, http.StatusBadGateway},
+		{"500 on GET", http.MethodGet, false, http.StatusInternalServerError, "", 1, http.StatusInternalServerError},
+		{"400 on GET", http.MethodGet, false, http.StatusBadRequest, "", 1, http.StatusBadRequest},
+		{"404 on GET", http.MethodGet, false, http.StatusNotFound, "", 1, http.StatusNotFound},
+		{"Retry-After within the cap", http.MethodGet, false, http.StatusTooManyRequests, "0", 3, http.StatusTooManyRequests},
+		{"Retry-After over the cap", http.MethodGet, false, http.StatusTooManyRequests, "3600", 1, http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			c := newTestClient(srv)
+			c.Retries = 2
+			c.RetryMaxWait = 50 * time.Millisecond
+
+			req, _ := http.NewRequest(tt.method, srv.URL, strings.NewReader("payload"))
+			if tt.idempotent {
+				markIdempotent(req)
+			}
+			resp, err := c.do(srv.Client(), req)
+			if err != nil {
+				t.Fatalf("do: %v", err)
+			}
+			resp.Body.Close()
+
+			if calls != tt.wantCalls {
+				t.Errorf("server called %d times, want %d", calls, tt.wantCalls)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+
+	tlsSrv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	tlsSrv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	tlsSrv.StartTLS()
+	defer tlsSrv.Close()
+
+	transportTests := []struct {
+		name      string
+		url       string
+		err       error // returned by the transport instead of dialing
+		wantCalls int
+	}{
+		{"connection refused", "http://api.example", syscall.ECONNREFUSED, 3},
+		{"DNS timeout", "http://api.example", &net.DNSError{Err: "i/o timeout", Name: "api.example", IsTimeout: true}, 3},
+		{"unknown host", "http://api.example", &net.DNSError{Err: "no such host", Name: "api.example", IsNotFound: true}, 1},
+		{"untrusted certificate", tlsSrv.URL, nil, 1},
+		{"unsupported scheme", "ftp://api.example/file", nil, 1},
+		{"missing host", "http:///file", nil, 1},
+	}
+
+	for _, tt := range transportTests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			hc := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				calls++
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				return http.DefaultTransport.RoundTrip(req)
+			})}
+
+			c := NewClient("sk_test_key")
+			c.Retries = 2
+			c.RetryMaxWait = time.Millisecond
+
+			req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.do(hc, req); err == nil {
+				t.Fatal("do succeeded, want an error")
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("sent %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestDoRecoversAfterTransientFailure(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		if len(bodies) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv)
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("payload"))
+	resp, err := c.do(srv.Client(), req)
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	for i, body := range bodies {
+		if body != "payload" {
+			t.Errorf("attempt %d sent body %q, want the full payload", i+1, body)
+		}
+	}
+}
+
+func TestDoSendsNonReplayableBodyOnce(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv)
+	// A body without GetBody cannot be rewound for another attempt.
+	body := io.MultiReader(strings.NewReader("stream"))
+	req, _ := http.NewRequest(http.MethodPut, srv.URL, body)
+	req.ContentLength = int64(len("stream"))
+
+	resp, err := c.do(srv.Client(), req)
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	resp.Body.Close()
+
+	if calls != 1 {
+		t.Errorf("server called %d times, want 1", calls)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", resp.StatusCode)
+	}
+}
+
+func TestDoStopsWhenContextEnds(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv)
+	c.RetryMaxWait = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+
+	start := time.Now()
+	_, err := c.do(srv.Client(), req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+		t.Errorf("do returned after %v, want it to stop at the deadline", elapsed)
+	}
+	if calls != 1 {
+		t.Errorf("server called %d times, want 1", calls)
+	}
+}
@@ -0,0 +1,195 @@
+package uploadthing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+)
+
+// Access control settings for uploaded files. Private files can only be
+// downloaded through a signed URL from RequestFileAccess.
+const (
+	ACLPublicRead = "public-read"
+	ACLPrivate    = "private"
+)
+
+// Content-Disposition settings for uploaded files, controlling whether
+// browsers display a file or save it.
+const (
+	ContentDispositionInline     = "inline"
+	ContentDispositionAttachment = "attachment"
+)
+
+// UploadOptions are the settings that apply to every file in an upload.
+type UploadOptions struct {
+	ACL                string
+	ContentDisposition string
+}
+
+type UploadFilesRequest struct {
+	Files              []FileMetadata `json:"files"`
+	ACL                string         `json:"acl,omitempty"`
+	ContentDisposition string         `json:"contentDisposition,omitempty"`
+}
+
+type FileMetadata struct {
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	Type     string `json:"type"`
+	CustomID string `json:"customId,omitempty"`
+}
+
+type UploadFilesResponse struct {
+	Data []PresignedUpload `json:"data"`
+}
+
+// PresignedUpload describes where to send a file's contents. Small files are
+// uploaded with a single presigned POST to URL; large files come with one
+// presigned PUT URL per chunk in URLs.
+type PresignedUpload struct {
+	URL                string            `json:"url"`
+	Fields             map[string]string `json:"fields"`
+	Key                string            `json:"key"`
+	FileName           string            `json:"fileName"`
+	FileType           string            `json:"fileType"`
+	FileUrl            string            `json:"fileUrl"`
+	ContentDisposition string            `json:"contentDisposition"`
+	URLs               []string          `json:"urls"`
+	UploadID           string            `json:"uploadId"`
+	ChunkSize          int64             `json:"chunkSize"`
+	ChunkCount         int               `json:"chunkCount"`
+}
+
+// IsMultipart reports whether the upload has to be sent in chunks.
+func (u PresignedUpload) IsMultipart() bool {
+	return len(u.URLs) > 0 && u.UploadID != ""
+}
+
+// UploadFiles requests presigned uploads for the files in req. UploadThing
+// returns them in the same order as the request.
+func (c *Client) UploadFiles(ctx context.Context, req UploadFilesRequest) ([]PresignedUpload, error) {
+	var resp UploadFilesResponse
+	if err := c.post(ctx, "/v6/uploadFiles", req, &resp); err != nil {
+		return nil, err
+	}
+
+	if len(resp.Data) != len(req.Files) {
+		return nil, fmt.Errorf("expected %d presigned uploads from UploadThing, got %d", len(req.Files), len(resp.Data))
+	}
+	return resp.Data, nil
+}
+
+// Upload presigns and uploads a single public file, returning where it was
+// stored.
+func (c *Client) Upload(ctx context.Context, metadata FileMetadata, file io.ReaderAt) (*PresignedUpload, error) {
+	return c.UploadWithOptions(ctx, metadata, file, UploadOptions{
+		ACL:                ACLPublicRead,
+		ContentDisposition: ContentDispositionInline,
+	})
+}
+
+// UploadWithOptions is like Upload but lets the caller choose the ACL and
+// Content-Disposition. Empty options use the app's defaults.
+func (c *Client) UploadWithOptions(ctx context.Context, metadata FileMetadata, file io.ReaderAt, opts UploadOptions) (*PresignedUpload, error) {
+	uploads, err := c.UploadFiles(ctx, UploadFilesRequest{
+		Files:              []FileMetadata{metadata},
+		ACL:                opts.ACL,
+		ContentDisposition: opts.ContentDisposition,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	upload := uploads[0]
+	if err := c.UploadPresigned(ctx, upload, metadata.Name, file, metadata.Size); err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// UploadPresigned sends the contents of file to a presigned upload, in chunks
+// if UploadThing asked for a multipart upload.
+func (c *Client) UploadPresigned(ctx context.Context, upload PresignedUpload, fileName string, file io.ReaderAt, fileSize int64) error {
+	if upload.IsMultipart() {
+		return c.uploadMultipart(ctx, upload, file, fileSize)
+	}
+	return c.uploadPresignedPost(ctx, upload, fileName, file, fileSize)
+}
+
+// uploadPresignedPost sends file as a form upload. Posting the same file to a
+// presigned URL again only overwrites it, so the request is marked idempotent
+// and its body is rebuilt for every retry.
+func (c *Client) uploadPresignedPost(ctx context.Context, presignedUpload PresignedUpload, fileName string, file io.ReaderAt, fileSize int64) error {
+	newBody, formContentType, contentLength, err := newMultipartFileBody(presignedUpload.Fields, "file", fileName, file, fileSize)
+	if err != nil {
+		return err
+	}
+
+	uploadFileReq, err := http.NewRequestWithContext(ctx, http.MethodPost, presignedUpload.URL, newBody())
+	if err != nil {
+		return fmt.Errorf("failed to create file upload request: %w", err)
+	}
+
+	uploadFileReq.ContentLength = contentLength
+	uploadFileReq.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(newBody()), nil
+	}
+	uploadFileReq.Header.Set("Content-Type", formContentType)
+	markIdempotent(uploadFileReq)
+
+	uploadFileResp, err := c.do(c.transferClient(), uploadFileReq)
+	if err != nil {
+		return fmt.Errorf("file upload request failed: %w", err)
+	}
+	defer uploadFileResp.Body.Close()
+
+	uploadFileRespBody, err := io.ReadAll(uploadFileResp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read file upload response: %w", err)
+	}
+
+	if uploadFileResp.StatusCode < 200 || uploadFileResp.StatusCode >= 300 {
+		return fmt.Errorf("file upload failed: status %d, response: %s", uploadFileResp.StatusCode, string(uploadFileRespBody))
+	}
+
+	return nil
+}
+
+// newMultipartFileBody builds a multipart/form-data body that streams the file
+// from disk instead of buffering it. Only the form fields and part headers are
+// held in memory, which also lets us compute the exact Content-Length. Each
+// call of the returned function starts a new copy of the body, with the same
+// boundary, from the beginning of the file.
+func newMultipartFileBody(fields map[string]string, fieldName, fileName string, file io.ReaderAt, fileSize int64) (func() io.Reader, string, int64, error) {
+	buf := &bytes.Buffer{}
+	writer := multipart.NewWriter(buf)
+
+	for key, value := range fields {
+		err := writer.WriteField(key, value)
+		if err != nil {
+			return nil, "", 0, fmt.Errorf("failed to write field %s: %w", key, err)
+		}
+	}
+
+	if _, err := writer.CreateFormFile(fieldName, fileName); err != nil {
+		return nil, "", 0, fmt.Errorf("failed to create form file: %w", err)
+	}
+
+	prefixLen := buf.Len()
+	if err := writer.Close(); err != nil {
+		return nil, "", 0, fmt.Errorf("failed to close multipart writer: %w", err)
+	}
+
+	prefix := buf.Bytes()[:prefixLen]
+	suffix := buf.Bytes()[prefixLen:]
+
+	body := func() io.Reader {
+		return io.MultiReader(bytes.NewReader(prefix), io.NewSectionReader(file, 0, fileSize), bytes.NewReader(suffix))
+	}
+	contentLength := int64(len(prefix)) + fileSize + int64(len(suffix))
+
+	return body, writer.FormDataContentType(), contentLength, nil
+}
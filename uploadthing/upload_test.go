@@ -0,0 +1,120 @@
+package uploadthing
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewMultipartFileBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  map[string]string
+		content string
+	}{
+		{"empty file", nil, ""},
+		{"with fields", map[string]string{"key": "abc", "policy": "p", "x-amz-signature": "sig"}, "hello world"},
+		{"binary", map[string]string{"key": "abc"}, strings.Repeat("\x00\xff", 40000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The reader holds more than fileSize bytes; only fileSize may be sent.
+			file := strings.NewReader(tt.content + "trailing bytes past the file size")
+			newBody, contentType, length, err := newMultipartFileBody(tt.fields, "file", "a b.txt", file, int64(len(tt.content)))
+			if err != nil {
+				t.Fatalf("newMultipartFileBody: %v", err)
+			}
+
+			data, err := io.ReadAll(newBody())
+			if err != nil {
+				t.Fatalf("reading body: %v", err)
+			}
+			if int64(len(data)) != length {
+				t.Errorf("Content-Length = %d, body has %d bytes", length, len(data))
+			}
+			again, _ := io.ReadAll(newBody())
+			if !bytes.Equal(again, data) {
+				t.Error("second copy of the body differs from the first")
+			}
+
+			mediaType, params, err := mime.ParseMediaType(contentType)
+			if err != nil || mediaType != "multipart/form-data" {
+				t.Fatalf("Content-Type = %q, %v", contentType, err)
+			}
+
+			form, err := multipart.NewReader(bytes.NewReader(data), params["boundary"]).ReadForm(1 << 20)
+			if err != nil {
+				t.Fatalf("parsing body: %v", err)
+			}
+			for key, value := range tt.fields {
+				if got := form.Value[key]; len(got) != 1 || got[0] != value {
+					t.Errorf("field %s = %v, want %q", key, got, value)
+				}
+			}
+
+			files := form.File["file"]
+			if len(files) != 1 || files[0].Filename != "a b.txt" {
+				t.Fatalf("file part = %+v", files)
+			}
+			f, err := files[0].Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			got, _ := io.ReadAll(f)
+			if string(got) != tt.content {
+				t.Errorf("file content has %d bytes, want %d", len(got), len(tt.content))
+			}
+		})
+	}
+}
+
+func TestUploadPresignedPostRetriesWithFullBody(t *testing.T) {
+	tests := []struct {
+		name string
+		fail func(w http.ResponseWriter)
+	}{
+		{"503", func(w http.ResponseWriter) { w.WriteHeader(http.StatusServiceUnavailable) }},
+		{"502", func(w http.ResponseWriter) { w.WriteHeader(http.StatusBadGateway) }},
+		{"connection reset", func(w http.ResponseWriter) {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bodies []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, _ := io.ReadAll(r.Body)
+				bodies = append(bodies, string(data))
+				if len(bodies) == 1 {
+					tt.fail(w)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer srv.Close()
+
+			content := "thumbnail bytes"
+			upload := PresignedUpload{URL: srv.URL, Fields: map[string]string{"key": "abc"}}
+			err := newTestClient(srv).UploadPresigned(context.Background(), upload, "thumb.png", strings.NewReader(content), int64(len(content)))
+			if err != nil {
+				t.Fatalf("UploadPresigned: %v", err)
+			}
+			if len(bodies) != 2 {
+				t.Fatalf("upload sent %d times, want 2", len(bodies))
+			}
+			if !strings.Contains(bodies[1], content) || bodies[0] != bodies[1] {
+				t.Error("retried upload did not resend the whole body")
+			}
+		})
+	}
+}